@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"context"
+	"expvar"
+	"runtime"
+	"sync/atomic"
+)
+
+// Bag 无序容器。不保证取出顺序，数据分散在多个无锁分段中以减少竞争，吞吐量高于 Queue。使用 NewBag 创建变量。
+//
+// 适合对象回收、任务池等不关心顺序的场景。
+type Bag[E any] struct {
+	putNext  uint32
+	_        [cacheLinePadSize - 4]byte
+	getNext  uint32
+	_        [cacheLinePadSize - 4]byte
+	shards   []*Stack[E]
+	counters *counters
+	notEmpty notifier
+}
+
+// NewBag 创建无序容器。capacity 每个分段的长度，调整规则同 New。分段个数为 GOMAXPROCS。
+// opts 中仅 WithStats 生效。
+func NewBag[E any](capacity uint32, opts ...Option) *Bag[E] {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	b := &Bag[E]{shards: make([]*Stack[E], runtime.GOMAXPROCS(0))}
+	for i := range b.shards {
+		b.shards[i] = NewStack[E](capacity)
+	}
+	if o.stats {
+		b.counters = &counters{}
+	}
+	return b
+}
+
+// Put 放入数据。所有分段均已满时返回错误 ErrQueueIsFull。
+func (b *Bag[E]) Put(value E) error {
+	start := int(atomic.AddUint32(&b.putNext, 1))
+	for i := range b.shards {
+		if _, err := b.shards[(start+i)%len(b.shards)].Push(value); err == nil {
+			if b.counters != nil {
+				atomic.AddUint64(&b.counters.puts, 1)
+			}
+			b.notEmpty.notify()
+			return nil
+		}
+	}
+	if b.counters != nil {
+		atomic.AddUint64(&b.counters.fullFailures, 1)
+	}
+	return ErrQueueIsFull
+}
+
+// TryGet 取出任意一个数据。无数据时返回 false。
+func (b *Bag[E]) TryGet() (E, bool) {
+	start := int(atomic.AddUint32(&b.getNext, 1))
+	for i := range b.shards {
+		if val, ok := b.shards[(start+i)%len(b.shards)].TryPop(); ok {
+			if b.counters != nil {
+				atomic.AddUint64(&b.counters.gets, 1)
+			}
+			return val, true
+		}
+	}
+	if b.counters != nil {
+		atomic.AddUint64(&b.counters.emptyFailures, 1)
+	}
+	var empty E
+	return empty, false
+}
+
+// Get 取出任意一个数据，无数据时等待，直至 ctx 结束。ctx 结束时返回 ctx.Err()。
+func (b *Bag[E]) Get(ctx context.Context) (E, error) {
+	var val E
+	err := await(ctx, &b.notEmpty, func() bool {
+		var ok bool
+		val, ok = b.TryGet()
+		return ok
+	})
+	return val, err
+}
+
+// Cap 返回所有分段的总长度。
+func (b *Bag[E]) Cap() uint32 {
+	var n uint32
+	for _, s := range b.shards {
+		n += s.Cap()
+	}
+	return n
+}
+
+// Len 返回数据个数。
+func (b *Bag[E]) Len() uint32 {
+	var n uint32
+	for _, s := range b.shards {
+		n += s.Len()
+	}
+	return n
+}
+
+// PublishExpvar 以 name 为名将容器状态发布到 expvar，内容同 Queue.PublishExpvar。name 已被发布时将 panic。
+func (b *Bag[E]) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		length := b.Len()
+		vars := map[string]any{
+			"len":       length,
+			"cap":       b.Cap(),
+			"fillRatio": float64(length) / float64(b.Cap()),
+		}
+		if b.counters != nil {
+			b.counters.export(vars)
+		}
+		return vars
+	}))
+}
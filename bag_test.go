@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestBag(t *testing.T) {
+	b := queue.NewBag[int](4, queue.WithStats())
+	if _, ok := b.TryGet(); ok {
+		t.Fatal("got from empty bag")
+	}
+	n := int(b.Cap())
+	seen := make(map[int]bool)
+	for i := 0; i < n; i++ {
+		if err := b.Put(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Put(n); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	for i := 0; i < n; i++ {
+		val, ok := b.TryGet()
+		if !ok || seen[val] {
+			t.Fatal("value lost or duplicated")
+		}
+		seen[val] = true
+	}
+
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		b.Put(7)
+	}()
+	if val, err := b.Get(context.Background()); err != nil || val != 7 {
+		t.Fatal("Get not woken")
+	}
+
+	name := expvarName(t)
+	b.PublishExpvar(name)
+	var vars struct {
+		Puts uint64 `json:"puts"`
+		Gets uint64 `json:"gets"`
+	}
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &vars); err != nil {
+		t.Fatal(err)
+	}
+	if vars.Puts != uint64(n+1) || vars.Gets != uint64(n+1) {
+		t.Fatal(expvar.Get(name).String())
+	}
+}
+
+func TestBagConcurrent(t *testing.T) {
+	const (
+		workers = 8
+		count   = 5000
+	)
+	b := queue.NewBag[int](64)
+	var mu sync.Mutex
+	sum := 0
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= count; {
+				if b.Put(i) == nil {
+					i++
+				} else {
+					runtime.Gosched()
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				val, _ := b.Get(context.Background())
+				mu.Lock()
+				sum += val
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if sum != workers*count*(count+1)/2 {
+		t.Fatalf("sum %d mismatch", sum)
+	}
+}
@@ -0,0 +1,258 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BlockingQueue 基于互斥锁和条件变量的队列，方法及语义与 Queue 一致。使用 NewBlocking 创建变量。
+//
+// 实现简单，等待者按条件变量唤醒，适合并发度不高、更看重简单与公平的场景。追求吞吐量时应使用 Queue。
+type BlockingQueue[E any] struct {
+	mu             sync.Mutex
+	notEmpty       *sync.Cond
+	notFull        *sync.Cond
+	capacity, mask uint32
+	head, length   uint32
+	closed         bool
+	elements       []E
+}
+
+// NewBlocking 创建队列。capacity 队列长度，调整规则同 New。
+func NewBlocking[E any](capacity uint32) *BlockingQueue[E] {
+	capacity = roundCapacity(capacity)
+	q := &BlockingQueue[E]{
+		capacity: capacity,
+		mask:     capacity - 1,
+		elements: make([]E, capacity),
+	}
+	q.notEmpty = sync.NewCond(&q.mu)
+	q.notFull = sync.NewCond(&q.mu)
+	return q
+}
+
+// Put 向队列尾部填充数据。返回剩余可填充数据个数。若队列已满返回错误 ErrQueueIsFull。队列已关闭时返回 ErrQueueIsClosed。
+func (q *BlockingQueue[E]) Put(value E) (uint32, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return 0, ErrQueueIsClosed
+	}
+	if q.length == q.capacity {
+		return 0, ErrQueueIsFull
+	}
+	return q.put(value), nil
+}
+
+// Get 取出队列头部数据。返回队列数据，队列剩余可取个数。当无数据可取时返回错误 ErrQueueIsEmpty。
+// 队列已关闭且无数据时返回 ErrQueueIsClosed。
+func (q *BlockingQueue[E]) Get() (E, uint32, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.length == 0 {
+		var empty E
+		if q.closed {
+			return empty, 0, ErrQueueIsClosed
+		}
+		return empty, 0, ErrQueueIsEmpty
+	}
+	val, used := q.get()
+	return val, used, nil
+}
+
+// PutEnough 向队列填充多个数据。返回实际填充数据个数，剩余可填充数据个数。
+func (q *BlockingQueue[E]) PutEnough(values ...E) (uint32, uint32) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return 0, 0
+	}
+	var n uint32
+	for _, v := range values {
+		if q.length == q.capacity {
+			break
+		}
+		q.put(v)
+		n++
+	}
+	return n, q.capacity - q.length
+}
+
+// GetEnough 从队列取出多个数据。返回队列数据，实际取出数据个数，剩余可取数据个数。
+func (q *BlockingQueue[E]) GetEnough(size uint32) ([]E, uint32, uint32) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if size > q.length {
+		size = q.length
+	}
+	res := make([]E, 0, size)
+	for i := uint32(0); i < size; i++ {
+		val, _ := q.get()
+		res = append(res, val)
+	}
+	return res, size, q.length
+}
+
+// PutContext 向队列尾部填充数据，若队列已满将等待，直至 ctx 结束。返回剩余可填充数据个数。
+// ctx 结束时返回 ctx.Err()，队列已关闭时返回 ErrQueueIsClosed。
+func (q *BlockingQueue[E]) PutContext(ctx context.Context, value E) (uint32, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	defer q.wakeOnDone(ctx, q.notFull)()
+	for {
+		if q.closed {
+			return 0, ErrQueueIsClosed
+		}
+		if q.length < q.capacity {
+			return q.put(value), nil
+		}
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		q.notFull.Wait()
+	}
+}
+
+// GetContext 取出队列头部数据，若队列无数据将等待，直至 ctx 结束。返回队列数据，队列剩余可取个数。
+// ctx 结束时返回 ctx.Err()，队列已关闭且无数据时返回 ErrQueueIsClosed。
+func (q *BlockingQueue[E]) GetContext(ctx context.Context) (E, uint32, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	defer q.wakeOnDone(ctx, q.notEmpty)()
+	for {
+		if q.length > 0 {
+			val, used := q.get()
+			return val, used, nil
+		}
+		var empty E
+		if q.closed {
+			return empty, 0, ErrQueueIsClosed
+		}
+		if err := ctx.Err(); err != nil {
+			return empty, 0, err
+		}
+		q.notEmpty.Wait()
+	}
+}
+
+// PutTimeout 向队列尾部填充数据，若队列已满最多等待 d。返回剩余可填充数据个数。超时仍未填充时返回 ErrQueueIsFull。
+func (q *BlockingQueue[E]) PutTimeout(value E, d time.Duration) (uint32, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	left, err := q.PutContext(ctx, value)
+	if err == context.DeadlineExceeded {
+		err = ErrQueueIsFull
+	}
+	return left, err
+}
+
+// GetTimeout 取出队列头部数据，若队列无数据最多等待 d。返回队列数据，队列剩余可取个数。超时仍无数据时返回 ErrQueueIsEmpty。
+func (q *BlockingQueue[E]) GetTimeout(d time.Duration) (E, uint32, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	val, used, err := q.GetContext(ctx)
+	if err == context.DeadlineExceeded {
+		err = ErrQueueIsEmpty
+	}
+	return val, used, err
+}
+
+// MustPut 向队列中塞数据，若队列已满将等待。返回剩余可填充数据个数。队列已关闭时，将以 ErrQueueIsClosed 触发 panic。
+func (q *BlockingQueue[E]) MustPut(value E) uint32 {
+	left, err := q.PutContext(context.Background(), value)
+	if err != nil {
+		panic(err)
+	}
+	return left
+}
+
+// MustGet 取出队列头部数据，若队列无数据将等待。返回队列数据，队列剩余可取个数。队列已关闭且无数据时，将以 ErrQueueIsClosed 触发 panic。
+func (q *BlockingQueue[E]) MustGet() (E, uint32) {
+	val, used, err := q.GetContext(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	return val, used
+}
+
+// Cap 返回队列长度。
+func (q *BlockingQueue[E]) Cap() uint32 {
+	return q.capacity
+}
+
+// Len 返回队列数据个数。
+func (q *BlockingQueue[E]) Len() uint32 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.length
+}
+
+// IsEmpty 判断队列是否有数据。
+func (q *BlockingQueue[E]) IsEmpty() bool {
+	return q.Len() == 0
+}
+
+// IsFull 判断队列是否已满。
+func (q *BlockingQueue[E]) IsFull() bool {
+	return q.Len() == q.capacity
+}
+
+// Close 关闭队列，语义同 Queue.Close。可重复调用。
+func (q *BlockingQueue[E]) Close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.notEmpty.Broadcast()
+	q.notFull.Broadcast()
+}
+
+// 调用方须持有锁且队列未满。
+func (q *BlockingQueue[E]) put(value E) uint32 {
+	q.elements[(q.head+q.length)&q.mask] = value
+	q.length++
+	q.notEmpty.Signal()
+	return q.capacity - q.length
+}
+
+// 调用方须持有锁且队列非空。
+func (q *BlockingQueue[E]) get() (E, uint32) {
+	elem := &q.elements[q.head&q.mask]
+	val := *elem
+	var empty E
+	*elem = empty
+	q.head++
+	q.length--
+	q.notFull.Signal()
+	return val, q.length
+}
+
+// ctx 结束时唤醒 cond 上的等待者，使其得以检查 ctx。返回停止监听的函数。调用方须持有锁。
+func (q *BlockingQueue[E]) wakeOnDone(ctx context.Context, cond *sync.Cond) func() {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			q.mu.Lock()
+			cond.Broadcast()
+			q.mu.Unlock()
+		case <-stop:
+		}
+	}()
+	return func() { close(stop) }
+}
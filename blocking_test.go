@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestBlockingQueue(t *testing.T) {
+	q := queue.NewBlocking[int](4)
+	if _, _, err := q.Get(); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+	if n, left := q.PutEnough(1, 2, 3, 4, 5); n != 4 || left != 0 {
+		t.Fatal("PutEnough mismatch")
+	}
+	if _, err := q.Put(5); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	if _, err := q.PutTimeout(5, time.Millisecond*10); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	vals, n, used := q.GetEnough(3)
+	if n != 3 || used != 1 || vals[0] != 1 || vals[2] != 3 {
+		t.Fatal("GetEnough mismatch")
+	}
+	q.MustGet()
+	if _, _, err := q.GetTimeout(time.Millisecond * 10); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+
+	done := make(chan error)
+	go func() {
+		_, _, err := q.GetContext(context.Background())
+		done <- err
+	}()
+	time.Sleep(time.Millisecond * 10)
+	q.Close()
+	if err := <-done; err != queue.ErrQueueIsClosed {
+		t.Fatal("err != ErrQueueIsClosed")
+	}
+	if _, err := q.Put(1); err != queue.ErrQueueIsClosed {
+		t.Fatal("err != ErrQueueIsClosed")
+	}
+}
+
+func TestBlockingQueueConcurrent(t *testing.T) {
+	const (
+		workers = 8
+		count   = 2000
+	)
+	q := queue.NewBlocking[int](16)
+	var sum int64
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= count; i++ {
+				q.MustPut(i)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				val, _ := q.MustGet()
+				atomic.AddInt64(&sum, int64(val))
+			}
+		}()
+	}
+	wg.Wait()
+	if sum != workers*count*(count+1)/2 {
+		t.Fatalf("sum %d mismatch", sum)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import "context"
+
+// BlockRing 定长块环。所有块在创建时一次性分配，填充时复制到空闲块，取出时返回块视图，调用 Release 后块才可再次使用，
+// 适合在网络收包与处理协程间传递数据包而不分配内存。使用 NewBlockRing 创建变量。
+type BlockRing struct {
+	blockSize uint32
+	buf       []byte
+	free      *Queue[uint32]
+	ready     *Queue[Block]
+}
+
+// Block 从 BlockRing 取出的数据块。
+type Block struct {
+	ring   *BlockRing
+	index  uint32
+	length uint32
+}
+
+// NewBlockRing 创建定长块环。blocks 块个数，blockSize 每块字节数，均不可为0。
+func NewBlockRing(blocks, blockSize uint32) *BlockRing {
+	if blocks == 0 || blockSize == 0 {
+		panic("blocks or blockSize is zero")
+	}
+	r := &BlockRing{
+		blockSize: blockSize,
+		buf:       make([]byte, uint64(blocks)*uint64(blockSize)),
+		free:      New[uint32](blocks),
+		ready:     New[Block](blocks),
+	}
+	for i := uint32(0); i < blocks; i++ {
+		_, _ = r.free.tryPut(i)
+	}
+	return r
+}
+
+// Put 将 p 复制到空闲块并填充到队列尾部。无空闲块时返回错误 ErrQueueIsFull，p 长度超过块大小时返回错误 ErrRecordTooLarge。
+func (r *BlockRing) Put(p []byte) error {
+	if uint64(len(p)) > uint64(r.blockSize) {
+		return ErrRecordTooLarge
+	}
+	index, _, err := r.free.Get()
+	if err != nil {
+		return ErrQueueIsFull
+	}
+	copy(r.block(index), p)
+	_, _ = r.ready.tryPut(Block{ring: r, index: index, length: uint32(len(p))})
+	return nil
+}
+
+// Get 取出队列头部数据块。无数据时返回错误 ErrQueueIsEmpty。
+func (r *BlockRing) Get() (Block, error) {
+	b, _, err := r.ready.Get()
+	return b, err
+}
+
+// GetContext 取出队列头部数据块，无数据时阻塞直到有数据或 ctx 结束。
+func (r *BlockRing) GetContext(ctx context.Context) (Block, error) {
+	b, _, err := r.ready.GetContext(ctx)
+	return b, err
+}
+
+// BlockSize 返回每块字节数。
+func (r *BlockRing) BlockSize() uint32 {
+	return r.blockSize
+}
+
+// Len 返回待取出数据块个数。
+func (r *BlockRing) Len() uint32 {
+	return r.ready.Len()
+}
+
+// Free 返回空闲块个数。
+func (r *BlockRing) Free() uint32 {
+	return r.free.Len()
+}
+
+func (r *BlockRing) block(index uint32) []byte {
+	start := uint64(index) * uint64(r.blockSize)
+	end := start + uint64(r.blockSize)
+	return r.buf[start:end:end]
+}
+
+// Bytes 返回块中数据。切片直接引用块内存，Release 后不可再使用。
+func (b Block) Bytes() []byte {
+	return b.ring.block(b.index)[:b.length]
+}
+
+// Release 归还数据块，每个块只能归还一次。
+func (b Block) Release() {
+	_, _ = b.ring.free.tryPut(b.index)
+}
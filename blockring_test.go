@@ -0,0 +1,105 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestBlockRing(t *testing.T) {
+	r := queue.NewBlockRing(2, 8)
+	if _, err := r.Get(); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+	if err := r.Put(make([]byte, 9)); err != queue.ErrRecordTooLarge {
+		t.Fatal("err != ErrRecordTooLarge")
+	}
+	src := []byte("abc")
+	if err := r.Put(src); err != nil {
+		t.Fatal(err)
+	}
+	src[0] = 'x'
+	if err := r.Put([]byte("12345678")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Put([]byte("z")); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+
+	b, err := r.Get()
+	if err != nil || string(b.Bytes()) != "abc" {
+		t.Fatal("block != abc")
+	}
+	if err = r.Put([]byte("z")); err != queue.ErrQueueIsFull {
+		t.Fatal("block reused before release")
+	}
+	b.Release()
+	if r.Free() != 1 {
+		t.Fatal("free != 1")
+	}
+	if err = r.Put([]byte("z")); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ = r.Get(); string(b.Bytes()) != "12345678" {
+		t.Fatal("block != 12345678")
+	}
+	b.Release()
+	if b, _ = r.Get(); string(b.Bytes()) != "z" {
+		t.Fatal("block != z")
+	}
+	b.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if _, err = r.GetContext(ctx); err != context.DeadlineExceeded {
+		t.Fatal("err != DeadlineExceeded")
+	}
+}
+
+func TestBlockRingConcurrent(t *testing.T) {
+	r := queue.NewBlockRing(4, 16)
+	const count = 5000
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < count; i++ {
+			b, err := r.GetContext(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if string(b.Bytes()) != strconv.Itoa(i) {
+				t.Errorf("block %s != %d", b.Bytes(), i)
+			}
+			b.Release()
+		}
+	}()
+	for i := 0; i < count; {
+		if r.Put([]byte(strconv.Itoa(i))) != nil {
+			time.Sleep(time.Microsecond)
+			continue
+		}
+		i++
+	}
+	wg.Wait()
+	if r.Free() != 4 {
+		t.Fatal("free != 4")
+	}
+}
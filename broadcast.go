@@ -0,0 +1,235 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// BroadcastPolicy 最慢的订阅者落后一整圈时 Put 的处理方式。
+type BroadcastPolicy int
+
+const (
+	// BroadcastGate 等待最慢的订阅者，Put 返回 ErrQueueIsFull，PutContext 等待其取出数据。默认使用。
+	BroadcastGate BroadcastPolicy = iota
+	// BroadcastDrop 不等待订阅者，直接覆盖最旧的数据。落后的订阅者将跳过被覆盖的数据，参见 Reader.Dropped。
+	BroadcastDrop
+)
+
+type (
+	// Broadcast 广播环。每个数据只写一次，每个订阅者都能取到订阅之后填充的全部数据。使用 NewBroadcast 创建变量。
+	//
+	// 每个订阅者持有独立的读取位置，互不影响。适合行情分发等一写多读的场景。
+	Broadcast[E any] struct {
+		mu             sync.RWMutex
+		capacity, mask uint32
+		policy         BroadcastPolicy
+		tail           uint64
+		elements       []E
+		readers        map[*Reader[E]]struct{}
+		notEmpty       notifier
+		notFull        notifier
+	}
+
+	// Reader 广播环的订阅者。使用 Broadcast.Subscribe 创建变量。
+	Reader[E any] struct {
+		b       *Broadcast[E]
+		cursor  uint64
+		dropped uint64
+	}
+)
+
+// NewBroadcast 创建广播环。capacity 环长度，调整规则同 New。policy 最慢的订阅者落后一整圈时的处理方式。
+func NewBroadcast[E any](capacity uint32, policy BroadcastPolicy) *Broadcast[E] {
+	capacity = roundCapacity(capacity)
+	return &Broadcast[E]{
+		capacity: capacity,
+		mask:     capacity - 1,
+		policy:   policy,
+		elements: make([]E, capacity),
+		readers:  make(map[*Reader[E]]struct{}),
+	}
+}
+
+// Subscribe 订阅广播环。订阅者从当前位置开始读取，只能取到订阅之后填充的数据。
+func (b *Broadcast[E]) Subscribe() *Reader[E] {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r := &Reader[E]{b: b, cursor: b.tail}
+	b.readers[r] = struct{}{}
+	return r
+}
+
+// Put 向所有订阅者发布数据。返回最慢的订阅者剩余可填充数据个数。
+// 使用 BroadcastGate 时，若最慢的订阅者落后一整圈返回错误 ErrQueueIsFull。
+func (b *Broadcast[E]) Put(value E) (uint32, error) {
+	b.mu.Lock()
+	used := b.used()
+	if b.policy == BroadcastGate && used == uint64(b.capacity) {
+		b.mu.Unlock()
+		return 0, ErrQueueIsFull
+	}
+	b.elements[b.tail&uint64(b.mask)] = value
+	b.tail++
+	if used < uint64(b.capacity) {
+		used++
+	}
+	b.mu.Unlock()
+	b.notEmpty.notify()
+	return b.capacity - uint32(used), nil
+}
+
+// PutContext 向所有订阅者发布数据，最慢的订阅者落后一整圈时等待，直至 ctx 结束。返回最慢的订阅者剩余可填充数据个数。
+// ctx 结束时返回 ctx.Err()。
+func (b *Broadcast[E]) PutContext(ctx context.Context, value E) (uint32, error) {
+	var left uint32
+	err := await(ctx, &b.notFull, func() bool {
+		var err error
+		left, err = b.Put(value)
+		return err == nil
+	})
+	return left, err
+}
+
+// ProducerSequence 返回已发布数据总数，即下一个数据的序号。可作为 Reader.SkipTo 的参数，使订阅者跳至最新位置。
+func (b *Broadcast[E]) ProducerSequence() uint64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.tail
+}
+
+// DropSlowest 将落后最多的订阅者的读取位置移至最新位置，以释放被其阻挡的位置，避免一个慢订阅者拖住整个广播环。
+// 返回该订阅者跳过的数据个数，没有订阅者或均无未读数据时返回0。多个订阅者同样落后时任选其一。
+//
+// 被跳过的数据对该订阅者而言已丢失，且不计入 Reader.Dropped。
+func (b *Broadcast[E]) DropSlowest() uint64 {
+	b.mu.Lock()
+	var slowest *Reader[E]
+	for r := range b.readers {
+		if slowest == nil || atomic.LoadUint64(&r.cursor) < atomic.LoadUint64(&slowest.cursor) {
+			slowest = r
+		}
+	}
+	var skipped uint64
+	if slowest != nil {
+		skipped = b.tail - atomic.LoadUint64(&slowest.cursor)
+		atomic.StoreUint64(&slowest.cursor, b.tail)
+	}
+	b.mu.Unlock()
+	if skipped > 0 {
+		b.notFull.notify()
+	}
+	return skipped
+}
+
+// 最慢的订阅者未读取的数据个数，不超过环长度。调用方须持有锁。
+func (b *Broadcast[E]) used() uint64 {
+	var used uint64
+	for r := range b.readers {
+		if n := b.tail - atomic.LoadUint64(&r.cursor); n > used {
+			used = n
+		}
+	}
+	if used > uint64(b.capacity) {
+		used = uint64(b.capacity)
+	}
+	return used
+}
+
+// Get 取出下一个数据。返回数据，该订阅者剩余可取个数。无数据可取时返回错误 ErrQueueIsEmpty。
+func (r *Reader[E]) Get() (E, uint32, error) {
+	b := r.b
+	b.mu.RLock()
+	for {
+		cursor := atomic.LoadUint64(&r.cursor)
+		if cursor == b.tail {
+			b.mu.RUnlock()
+			var empty E
+			return empty, 0, ErrQueueIsEmpty
+		}
+		next := cursor
+		if b.tail-cursor > uint64(b.capacity) {
+			next = b.tail - uint64(b.capacity)
+		}
+		val := b.elements[next&uint64(b.mask)]
+		if atomic.CompareAndSwapUint64(&r.cursor, cursor, next+1) {
+			atomic.AddUint64(&r.dropped, next-cursor)
+			used := b.tail - next - 1
+			b.mu.RUnlock()
+			b.notFull.notify()
+			return val, uint32(used), nil
+		}
+	}
+}
+
+// GetContext 取出下一个数据，无数据时等待，直至 ctx 结束。返回数据，该订阅者剩余可取个数。ctx 结束时返回 ctx.Err()。
+func (r *Reader[E]) GetContext(ctx context.Context) (E, uint32, error) {
+	var (
+		val  E
+		used uint32
+	)
+	err := await(ctx, &r.b.notEmpty, func() bool {
+		var err error
+		val, used, err = r.Get()
+		return err == nil
+	})
+	return val, used, err
+}
+
+// SkipTo 将读取位置移至序号 seq，即下一次 Get 取出第 seq 个发布的数据，序号从零开始，参见 Broadcast.ProducerSequence。
+// 返回跳过的数据个数。seq 不超过当前读取位置时不移动，超过已发布数据总数时移至最新位置。
+//
+// 被跳过的数据对该订阅者而言已丢失，且不计入 Dropped。
+func (r *Reader[E]) SkipTo(seq uint64) uint64 {
+	b := r.b
+	b.mu.Lock()
+	if seq > b.tail {
+		seq = b.tail
+	}
+	var skipped uint64
+	if cursor := atomic.LoadUint64(&r.cursor); seq > cursor {
+		skipped = seq - cursor
+		atomic.StoreUint64(&r.cursor, seq)
+	}
+	b.mu.Unlock()
+	if skipped > 0 {
+		b.notFull.notify()
+	}
+	return skipped
+}
+
+// Len 返回该订阅者剩余可取数据个数。
+func (r *Reader[E]) Len() uint32 {
+	r.b.mu.RLock()
+	defer r.b.mu.RUnlock()
+	n := r.b.tail - atomic.LoadUint64(&r.cursor)
+	if n > uint64(r.b.capacity) {
+		n = uint64(r.b.capacity)
+	}
+	return uint32(n)
+}
+
+// Dropped 返回该订阅者因落后被覆盖而跳过的数据个数。仅使用 BroadcastDrop 时可能非零。
+func (r *Reader[E]) Dropped() uint64 {
+	return atomic.LoadUint64(&r.dropped)
+}
+
+// Unsubscribe 取消订阅，此后该订阅者不再阻挡填充。可重复调用。
+func (r *Reader[E]) Unsubscribe() {
+	r.b.mu.Lock()
+	delete(r.b.readers, r)
+	r.b.mu.Unlock()
+	r.b.notFull.notify()
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestBroadcastGate(t *testing.T) {
+	b := queue.NewBroadcast[int](4, queue.BroadcastGate)
+	fast, slow := b.Subscribe(), b.Subscribe()
+	for i := 0; i < 4; i++ {
+		if _, err := b.Put(i); err != nil {
+			t.Fatal(err)
+		}
+		fast.Get()
+	}
+	if _, err := b.Put(4); err != queue.ErrQueueIsFull {
+		t.Fatal("slow reader did not gate Put")
+	}
+	if val, used, _ := slow.Get(); val != 0 || used != 3 {
+		t.Fatal("slow Get mismatch")
+	}
+	if _, err := b.Put(4); err != nil {
+		t.Fatal(err)
+	}
+	slow.Unsubscribe()
+	for i := 5; i < 8; i++ {
+		if _, err := b.Put(i); err != nil {
+			t.Fatal("unsubscribed reader still gates")
+		}
+	}
+	if fast.Len() != 4 {
+		t.Fatal("fast Len != 4")
+	}
+}
+
+func TestBroadcastDropSlowest(t *testing.T) {
+	b := queue.NewBroadcast[int](4, queue.BroadcastGate)
+	if b.DropSlowest() != 0 {
+		t.Fatal("skipped without readers")
+	}
+	fast, slow := b.Subscribe(), b.Subscribe()
+	for i := 0; i < 4; i++ {
+		b.Put(i)
+		fast.Get()
+	}
+	slow.Get()
+	if _, err := b.Put(4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Put(5); err != queue.ErrQueueIsFull {
+		t.Fatal("slow reader did not gate Put")
+	}
+	if skipped := b.DropSlowest(); skipped != 4 {
+		t.Fatal("skipped != 4")
+	}
+	if _, err := b.Put(5); err != nil {
+		t.Fatal("DropSlowest did not unblock Put")
+	}
+	if val, _, _ := slow.Get(); val != 5 {
+		t.Fatal("val != 5")
+	}
+	if val, _, _ := fast.Get(); val != 4 || slow.Dropped() != 0 {
+		t.Fatal("fast reader affected")
+	}
+}
+
+func TestReaderSkipTo(t *testing.T) {
+	b := queue.NewBroadcast[int](8, queue.BroadcastGate)
+	r := b.Subscribe()
+	for i := 0; i < 5; i++ {
+		b.Put(i)
+	}
+	if skipped := r.SkipTo(2); skipped != 2 {
+		t.Fatal("skipped != 2")
+	}
+	if r.SkipTo(1) != 0 {
+		t.Fatal("moved backwards")
+	}
+	if val, _, _ := r.Get(); val != 2 {
+		t.Fatal("val != 2")
+	}
+	if skipped := r.SkipTo(100); skipped != 2 || b.ProducerSequence() != 5 || r.Len() != 0 {
+		t.Fatal("skipped != 2")
+	}
+}
+
+func TestBroadcastDrop(t *testing.T) {
+	b := queue.NewBroadcast[int](4, queue.BroadcastDrop)
+	r := b.Subscribe()
+	for i := 0; i < 10; i++ {
+		if _, err := b.Put(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if val, _, _ := r.Get(); val != 6 {
+		t.Fatal("lagging reader did not skip ahead")
+	}
+	if r.Dropped() != 6 {
+		t.Fatal("Dropped != 6")
+	}
+}
+
+func TestBroadcastConcurrent(t *testing.T) {
+	const (
+		readers = 4
+		count   = 2000
+	)
+	b := queue.NewBroadcast[int](16, queue.BroadcastGate)
+	subs := make([]*queue.Reader[int], readers)
+	for i := range subs {
+		subs[i] = b.Subscribe()
+	}
+	wg := sync.WaitGroup{}
+	for _, r := range subs {
+		wg.Add(1)
+		go func(r *queue.Reader[int]) {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				val, _, err := r.GetContext(context.Background())
+				if err != nil || val != i {
+					t.Errorf("val %d != %d", val, i)
+					return
+				}
+			}
+		}(r)
+	}
+	for i := 0; i < count; i++ {
+		if _, err := b.PutContext(context.Background(), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+}
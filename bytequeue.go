@@ -0,0 +1,160 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"encoding/binary"
+	"sync"
+)
+
+// ErrRecordTooLarge 表明记录长度超过字节队列可容纳的最大长度。
+var ErrRecordTooLarge = newError("记录长度超过队列容量", "record exceeds queue capacity")
+
+const (
+	recordHeaderSize = 4
+	recordWrapMarker = ^uint32(0)
+)
+
+// ByteQueue 字节队列，在连续的环形字节数组中保存变长记录，每条记录以4字节长度为前缀。
+// 与 Queue[[]byte] 相比，填充记录时不需要为每条记录分配内存。使用 NewByteQueue 创建变量。
+//
+// 为使 Peek 返回连续的切片，记录不会跨越数组末尾，末尾剩余空间不足时将跳过并从数组头部写入。
+type ByteQueue struct {
+	mu             sync.Mutex
+	capacity, mask uint32
+	head, tail     uint32
+	records        uint32
+	buf            []byte
+}
+
+// NewByteQueue 创建字节队列。capacity 字节数组长度，调整规则同 New。单条记录最大长度为 capacity-4。
+func NewByteQueue(capacity uint32) *ByteQueue {
+	capacity = roundCapacity(capacity)
+	if capacity < recordHeaderSize*2 {
+		capacity = recordHeaderSize * 2
+	}
+	return &ByteQueue{
+		capacity: capacity,
+		mask:     capacity - 1,
+		buf:      make([]byte, capacity),
+	}
+}
+
+// PutRecord 向队列尾部填充一条记录，p 将被复制到队列中。
+// 若剩余空间不足返回错误 ErrQueueIsFull，若记录长度超过最大长度返回错误 ErrRecordTooLarge。
+func (q *ByteQueue) PutRecord(p []byte) error {
+	if uint64(len(p)) > uint64(q.capacity-recordHeaderSize) {
+		return ErrRecordTooLarge
+	}
+	size := recordHeaderSize + uint32(len(p))
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	index := q.tail & q.mask
+	pad := uint32(0)
+	if q.capacity-index < size {
+		pad = q.capacity - index
+	}
+	if q.capacity-(q.tail-q.head) < pad+size {
+		return ErrQueueIsFull
+	}
+	if pad > 0 {
+		if pad >= recordHeaderSize {
+			binary.LittleEndian.PutUint32(q.buf[index:], recordWrapMarker)
+		}
+		q.tail += pad
+		index = 0
+	}
+	binary.LittleEndian.PutUint32(q.buf[index:], uint32(len(p)))
+	copy(q.buf[index+recordHeaderSize:], p)
+	q.tail += size
+	q.records++
+	return nil
+}
+
+// GetRecord 取出队列头部记录，记录内容追加到 buf[:0] 后返回，buf 容量足够时不分配内存。无记录时返回错误 ErrQueueIsEmpty。
+func (q *ByteQueue) GetRecord(buf []byte) ([]byte, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	record, err := q.peek()
+	if err != nil {
+		return buf, err
+	}
+	buf = append(buf[:0], record...)
+	q.discard(record)
+	return buf, nil
+}
+
+// Peek 返回队列头部记录但不取出。返回的切片直接引用队列内部数组，仅在调用 Discard 或 GetRecord 前有效，不可修改。
+// 无记录时返回错误 ErrQueueIsEmpty。
+func (q *ByteQueue) Peek() ([]byte, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.peek()
+}
+
+// Discard 丢弃队列头部记录。无记录时返回错误 ErrQueueIsEmpty。
+func (q *ByteQueue) Discard() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	record, err := q.peek()
+	if err != nil {
+		return err
+	}
+	q.discard(record)
+	return nil
+}
+
+// Cap 返回字节数组长度。
+func (q *ByteQueue) Cap() uint32 {
+	return q.capacity
+}
+
+// Len 返回队列记录个数。
+func (q *ByteQueue) Len() uint32 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.records
+}
+
+// Size 返回队列已占用字节数，包括长度前缀和数组末尾跳过的空间。
+func (q *ByteQueue) Size() uint32 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.tail - q.head
+}
+
+// 跳过数组末尾的填充，返回头部记录。
+func (q *ByteQueue) peek() ([]byte, error) {
+	if q.records == 0 {
+		return nil, ErrQueueIsEmpty
+	}
+	index := q.head & q.mask
+	if q.capacity-index < recordHeaderSize ||
+		binary.LittleEndian.Uint32(q.buf[index:]) == recordWrapMarker {
+		q.head += q.capacity - index
+		index = 0
+	}
+	length := binary.LittleEndian.Uint32(q.buf[index:])
+	start := index + recordHeaderSize
+	return q.buf[start : start+length : start+length], nil
+}
+
+// 移除 peek 返回的头部记录。
+func (q *ByteQueue) discard(record []byte) {
+	q.head += recordHeaderSize + uint32(len(record))
+	q.records--
+	if q.records == 0 {
+		q.head, q.tail = 0, 0
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestByteQueue(t *testing.T) {
+	q := queue.NewByteQueue(32)
+	if _, err := q.Peek(); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+	if err := q.PutRecord(make([]byte, 29)); err != queue.ErrRecordTooLarge {
+		t.Fatal("err != ErrRecordTooLarge")
+	}
+	if err := q.PutRecord([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.PutRecord(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.PutRecord([]byte("0123456789")); err != nil {
+		t.Fatal(err)
+	}
+	if q.Len() != 3 || q.Size() != 27 {
+		t.Fatal("len != 3")
+	}
+	if err := q.PutRecord([]byte("ab")); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+
+	record, err := q.Peek()
+	if err != nil || string(record) != "hello" {
+		t.Fatal("peek != hello")
+	}
+	buf := make([]byte, 0, 16)
+	got, err := q.GetRecord(buf)
+	if err != nil || string(got) != "hello" || &got[0] != &buf[:1][0] {
+		t.Fatal("get != hello")
+	}
+	if got, err = q.GetRecord(got); err != nil || len(got) != 0 {
+		t.Fatal("get != empty")
+	}
+
+	// 末尾剩余5字节，不足以容纳该记录，将从数组头部写入。
+	if err = q.PutRecord([]byte("abcdefgh")); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = q.GetRecord(nil); string(got) != "0123456789" {
+		t.Fatal("get != 0123456789")
+	}
+	if record, _ = q.Peek(); string(record) != "abcdefgh" {
+		t.Fatal("peek != abcdefgh")
+	}
+	if err = q.Discard(); err != nil || q.Len() != 0 || q.Size() != 0 {
+		t.Fatal("queue not empty")
+	}
+	if err = q.Discard(); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+	if err = q.PutRecord(make([]byte, 28)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestByteQueueConcurrent(t *testing.T) {
+	q := queue.NewByteQueue(256)
+	const count = 10000
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < count; {
+			if q.PutRecord([]byte(strconv.Itoa(i))) != nil {
+				runtime.Gosched()
+				continue
+			}
+			i++
+		}
+	}()
+	var buf []byte
+	for i := 0; i < count; {
+		var err error
+		buf, err = q.GetRecord(buf)
+		if err != nil {
+			runtime.Gosched()
+			continue
+		}
+		if !bytes.Equal(buf, []byte(strconv.Itoa(i))) {
+			t.Fatalf("record %s != %d", buf, i)
+		}
+		i++
+	}
+	wg.Wait()
+}
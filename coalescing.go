@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+type (
+	// CoalescingQueue 合并队列。同一键排队期间再次填充将替换为最新的值，取出时按键首次排队的顺序返回最新值。
+	// 使用 NewCoalescingQueue 创建变量。
+	//
+	// 适合只关心最新状态的界面刷新、状态同步等场景。
+	CoalescingQueue[K comparable, V any] struct {
+		q *DedupQueue[K, coalescingEntry[K, V]]
+	}
+
+	coalescingEntry[K comparable, V any] struct {
+		key   K
+		value V
+	}
+)
+
+// NewCoalescingQueue 创建合并队列。capacity 队列长度，调整规则同 New。
+func NewCoalescingQueue[K comparable, V any](capacity uint32) *CoalescingQueue[K, V] {
+	return &CoalescingQueue[K, V]{
+		q: NewDedupQueue[K, coalescingEntry[K, V]](capacity,
+			func(e coalescingEntry[K, V]) K { return e.key },
+			func(_, incoming coalescingEntry[K, V]) coalescingEntry[K, V] { return incoming }),
+	}
+}
+
+// Put 填充键 key 的值。返回剩余可填充数据个数。若队列已满返回错误 ErrQueueIsFull。
+// 该键已在排队时替换为 value，不占用新的位置，并返回成功。
+func (q *CoalescingQueue[K, V]) Put(key K, value V) (uint32, error) {
+	return q.q.Put(coalescingEntry[K, V]{key: key, value: value})
+}
+
+// Get 取出最早排队的键及其最新值。返回键，值，队列剩余可取个数。当无数据可取时返回错误 ErrQueueIsEmpty。
+func (q *CoalescingQueue[K, V]) Get() (K, V, uint32, error) {
+	e, used, err := q.q.Get()
+	return e.key, e.value, used, err
+}
+
+// Pending 判断键 key 是否正在排队。
+func (q *CoalescingQueue[K, V]) Pending(key K) bool {
+	return q.q.Pending(key)
+}
+
+// Cap 返回队列长度。
+func (q *CoalescingQueue[K, V]) Cap() uint32 {
+	return q.q.Cap()
+}
+
+// Len 返回队列数据个数。
+func (q *CoalescingQueue[K, V]) Len() uint32 {
+	return q.q.Len()
+}
+
+// IsEmpty 判断队列是否有数据。
+func (q *CoalescingQueue[K, V]) IsEmpty() bool {
+	return q.q.IsEmpty()
+}
@@ -0,0 +1,39 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestCoalescingQueue(t *testing.T) {
+	q := queue.NewCoalescingQueue[string, int](4)
+	q.Put("x", 1)
+	q.Put("y", 1)
+	q.Put("x", 2)
+	q.Put("x", 3)
+	if q.Len() != 2 {
+		t.Fatal("Len != 2")
+	}
+	if key, val, used, _ := q.Get(); key != "x" || val != 3 || used != 1 {
+		t.Fatal("Get != x3")
+	}
+	if key, val, _, _ := q.Get(); key != "y" || val != 1 {
+		t.Fatal("Get != y1")
+	}
+	if _, _, _, err := q.Get(); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// ErrMultipleProducers 表明单生产者队列被多个协程填充数据。
+	ErrMultipleProducers = newError("多个协程填充数据", "multiple goroutines put data")
+	// ErrMultipleConsumers 表明单消费者队列被多个协程取出数据。
+	ErrMultipleConsumers = newError("多个协程取出数据", "multiple goroutines get data")
+)
+
+type callerTracker struct {
+	producer, consumer             uint64
+	singleProducer, singleConsumer bool
+	onViolation                    func(error)
+}
+
+type consumeLog struct {
+	mu   sync.Mutex
+	seqs []uint64
+	last uint64
+}
+
+// 记录序号 seq。序号按 uint32 回绕，以与最近记录的差值还原为64位序号。
+func (l *consumeLog) record(seq uint32) {
+	l.mu.Lock()
+	full := uint64(int64(l.last) + int64(int32(seq-uint32(l.last))))
+	if full > l.last {
+		l.last = full
+	}
+	l.seqs = append(l.seqs, full-1)
+	l.mu.Unlock()
+}
+
+// ConsumeLog 返回被取出数据的序号，按取出完成的先后排列。序号从零开始，同 GetSequenced 返回的序号减一，不受 Compact、Resize 影响。
+// RemoveWhere 移除的数据视为已取出，同样记录。需使用 WithConsumeLog 创建队列，否则返回 nil。
+func (q *Queue[E]) ConsumeLog() []uint64 {
+	if q.consumeLog == nil {
+		return nil
+	}
+	q.consumeLog.mu.Lock()
+	defer q.consumeLog.mu.Unlock()
+	return append([]uint64(nil), q.consumeLog.seqs...)
+}
+
+// VerifyConsumeLog 校验取出记录中没有重复的序号，且序号连续无遗漏。
+func (q *Queue[E]) VerifyConsumeLog() error {
+	seqs := q.ConsumeLog()
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	for i := 1; i < len(seqs); i++ {
+		switch {
+		case seqs[i] == seqs[i-1]:
+			return fmt.Errorf(localize("序号 %d 被重复取出", "sequence %d consumed more than once"), seqs[i])
+		case seqs[i] != seqs[i-1]+1:
+			return fmt.Errorf(localize("序号 %d 至 %d 未被取出", "sequences %d to %d not consumed"), seqs[i-1]+1, seqs[i]-1)
+		}
+	}
+	return nil
+}
+
+func (c *callerTracker) trackProducer() {
+	if c.singleProducer {
+		c.track(&c.producer, ErrMultipleProducers)
+	}
+}
+
+func (c *callerTracker) trackConsumer() {
+	if c.singleConsumer {
+		c.track(&c.consumer, ErrMultipleConsumers)
+	}
+}
+
+func (c *callerTracker) track(owner *uint64, err error) {
+	id := goroutineID()
+	if atomic.CompareAndSwapUint64(owner, 0, id) || atomic.LoadUint64(owner) == id {
+		return
+	}
+	if c.onViolation == nil {
+		panic(err)
+	}
+	c.onViolation(err)
+}
+
+// 从调用栈首行“goroutine N [running]:”中解析协程标识。
+func goroutineID() uint64 {
+	var buf [64]byte
+	line := buf[:runtime.Stack(buf[:], false)]
+	line = bytes.TrimPrefix(line, []byte("goroutine "))
+	if i := bytes.IndexByte(line, ' '); i > 0 {
+		line = line[:i]
+	}
+	id, _ := strconv.ParseUint(string(line), 10, 64)
+	return id
+}
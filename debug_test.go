@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"sync"
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestConsumeLog(t *testing.T) {
+	const (
+		capacity = 1 << 6
+		workers  = 8
+		count    = 1000
+	)
+	q := queue.New[int](capacity, queue.WithConsumeLog())
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < count; j++ {
+				q.MustPut(j)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < count; j++ {
+				q.MustGet()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := len(q.ConsumeLog()); n != workers*count {
+		t.Fatalf("len(ConsumeLog) %d != %d", n, workers*count)
+	}
+	if err := q.VerifyConsumeLog(); err != nil {
+		t.Fatal(err)
+	}
+
+	if queue.New[int](2).ConsumeLog() != nil {
+		t.Fatal("ConsumeLog != nil")
+	}
+}
+
+func TestConsumeLogAcrossRebuild(t *testing.T) {
+	q := queue.New[int](4, queue.WithConsumeLog(), queue.WithResizable())
+	q.PutEnough(1, 2, 3)
+	q.Get()
+	q.Compact()
+	q.Get()
+	q.PutEnough(4, 5)
+	if removed, _ := q.RemoveWhere(func(v int) bool { return v == 4 }); removed != 1 {
+		t.Fatal("removed != 1")
+	}
+	if err := q.Resize(16); err != nil {
+		t.Fatal(err)
+	}
+	q.GetEnough(2)
+	log := q.ConsumeLog()
+	if len(log) != 5 {
+		t.Fatal("len(log) != 5")
+	}
+	if err := q.VerifyConsumeLog(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCallerTracking(t *testing.T) {
+	var (
+		mu         sync.Mutex
+		violations []error
+	)
+	q := queue.New[int](8, queue.WithCallerTracking(false, true, func(err error) {
+		mu.Lock()
+		violations = append(violations, err)
+		mu.Unlock()
+	}))
+	wg := sync.WaitGroup{}
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.MustPut(1)
+		}()
+	}
+	wg.Wait()
+	q.MustGet()
+	q.MustGet()
+	if len(violations) != 0 {
+		t.Fatal("unexpected violation")
+	}
+
+	q.MustPut(1)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		q.MustGet()
+	}()
+	wg.Wait()
+	if len(violations) != 1 || violations[0] != queue.ErrMultipleConsumers {
+		t.Fatal("violation not detected")
+	}
+
+	q = queue.New[int](8, queue.WithCallerTracking(true, false, nil))
+	q.MustPut(1)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer func() {
+			if p := recover(); p != queue.ErrMultipleProducers {
+				t.Error("p != ErrMultipleProducers")
+			}
+		}()
+		q.MustPut(2)
+	}()
+	wg.Wait()
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import "sync"
+
+// DedupQueue 去重队列。同一键的数据在被取出前只排队一次，重复填充的数据被合并或丢弃。使用 NewDedupQueue 创建变量。
+//
+// 适合合并冗余的刷新、更新事件。数据被取出后其键随即释放，之后可再次排队。
+type DedupQueue[K comparable, E any] struct {
+	mu      sync.Mutex
+	keyOf   func(E) K
+	merge   func(pending, incoming E) E
+	pending map[K]E
+	order   *Queue[K]
+}
+
+// NewDedupQueue 创建去重队列。capacity 队列长度，调整规则同 New。keyOf 计算数据的键。
+// merge 合并排队中的数据与重复填充的数据，返回值替换排队中的数据，排队位置不变；为 nil 时丢弃重复填充的数据。
+func NewDedupQueue[K comparable, E any](capacity uint32, keyOf func(E) K, merge func(pending, incoming E) E) *DedupQueue[K, E] {
+	return &DedupQueue[K, E]{
+		keyOf:   keyOf,
+		merge:   merge,
+		pending: make(map[K]E),
+		order:   New[K](capacity),
+	}
+}
+
+// Put 向队列尾部填充数据。返回剩余可填充数据个数。若队列已满返回错误 ErrQueueIsFull。
+// 同一键的数据已在排队时，按 merge 合并或丢弃，不占用新的位置，并返回成功。
+func (q *DedupQueue[K, E]) Put(value E) (uint32, error) {
+	key := q.keyOf(value)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if old, ok := q.pending[key]; ok {
+		if q.merge != nil {
+			q.pending[key] = q.merge(old, value)
+		}
+		return q.order.Cap() - q.order.Len(), nil
+	}
+	left, err := q.order.Put(key)
+	if err != nil {
+		return 0, err
+	}
+	q.pending[key] = value
+	return left, nil
+}
+
+// Get 取出队列头部数据，并释放其键。返回队列数据，队列剩余可取个数。当无数据可取时返回错误 ErrQueueIsEmpty。
+func (q *DedupQueue[K, E]) Get() (E, uint32, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	key, used, err := q.order.Get()
+	if err != nil {
+		var empty E
+		return empty, 0, err
+	}
+	val := q.pending[key]
+	delete(q.pending, key)
+	return val, used, nil
+}
+
+// Pending 判断键为 key 的数据是否正在排队。
+func (q *DedupQueue[K, E]) Pending(key K) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	_, ok := q.pending[key]
+	return ok
+}
+
+// Cap 返回队列长度。
+func (q *DedupQueue[K, E]) Cap() uint32 {
+	return q.order.Cap()
+}
+
+// Len 返回队列数据个数。
+func (q *DedupQueue[K, E]) Len() uint32 {
+	return q.order.Len()
+}
+
+// IsEmpty 判断队列是否有数据。
+func (q *DedupQueue[K, E]) IsEmpty() bool {
+	return q.order.IsEmpty()
+}
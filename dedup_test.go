@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestDedupQueue(t *testing.T) {
+	type event struct {
+		id    string
+		count int
+	}
+	keyOf := func(e event) string { return e.id }
+
+	q := queue.NewDedupQueue[string, event](4, keyOf, nil)
+	q.Put(event{"a", 1})
+	q.Put(event{"b", 1})
+	q.Put(event{"a", 2})
+	if q.Len() != 2 || !q.Pending("a") {
+		t.Fatal("duplicate not dropped")
+	}
+	if val, _, _ := q.Get(); val.id != "a" || val.count != 1 {
+		t.Fatal("val != a1")
+	}
+	if q.Pending("a") {
+		t.Fatal("key not released")
+	}
+	q.Put(event{"a", 3})
+	if q.Len() != 2 {
+		t.Fatal("released key not requeued")
+	}
+
+	q = queue.NewDedupQueue[string, event](4, keyOf, func(pending, incoming event) event {
+		pending.count += incoming.count
+		return pending
+	})
+	q.Put(event{"a", 1})
+	q.Put(event{"b", 1})
+	q.Put(event{"a", 2})
+	if val, _, _ := q.Get(); val.id != "a" || val.count != 3 {
+		t.Fatal("val != a3")
+	}
+
+	q = queue.NewDedupQueue[string, event](2, keyOf, nil)
+	q.Put(event{"a", 1})
+	q.Put(event{"b", 1})
+	if _, err := q.Put(event{"c", 1}); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	if _, err := q.Put(event{"a", 1}); err != nil {
+		t.Fatal("duplicate rejected on full queue")
+	}
+}
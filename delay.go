@@ -0,0 +1,139 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DelayQueue 延迟队列。数据到期后才可被取出，先到期者先取出。使用 NewDelayQueue 创建变量。
+//
+// 适合重试调度等场景。内部为互斥锁保护的二叉堆，以到期时刻排序。
+type DelayQueue[E any] struct {
+	mu       sync.Mutex
+	capacity uint32
+	heap     priorityHeap[E]
+	changed  notifier
+}
+
+// NewDelayQueue 创建延迟队列。capacity 队列长度，调整规则同 New。
+func NewDelayQueue[E any](capacity uint32) *DelayQueue[E] {
+	capacity = roundCapacity(capacity)
+	return &DelayQueue[E]{
+		capacity: capacity,
+		heap:     priorityHeap[E]{items: make([]priorityItem[E], 0, capacity)},
+	}
+}
+
+// PutAt 填充数据，数据在 t 时刻到期。返回剩余可填充数据个数。若队列已满返回错误 ErrQueueIsFull。
+func (q *DelayQueue[E]) PutAt(value E, t time.Time) (uint32, error) {
+	q.mu.Lock()
+	if uint32(q.heap.len()) == q.capacity {
+		q.mu.Unlock()
+		return 0, ErrQueueIsFull
+	}
+	// 到期时刻越早优先级越高。
+	q.heap.push(-t.UnixNano(), value)
+	left := q.capacity - uint32(q.heap.len())
+	q.mu.Unlock()
+	q.changed.notify()
+	return left, nil
+}
+
+// PutAfter 填充数据，数据在 delay 之后到期。返回剩余可填充数据个数。若队列已满返回错误 ErrQueueIsFull。
+func (q *DelayQueue[E]) PutAfter(value E, delay time.Duration) (uint32, error) {
+	return q.PutAt(value, time.Now().Add(delay))
+}
+
+// Get 取出最早到期的数据。返回队列数据，队列剩余数据个数（含未到期数据）。无到期数据时返回错误 ErrQueueIsEmpty。
+func (q *DelayQueue[E]) Get() (E, uint32, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.heap.len() == 0 || q.untilDue() > 0 {
+		var empty E
+		return empty, 0, ErrQueueIsEmpty
+	}
+	return q.heap.pop(), uint32(q.heap.len()), nil
+}
+
+// GetContext 取出最早到期的数据，无到期数据时等待，直至有数据到期或 ctx 结束。
+// 返回队列数据，队列剩余数据个数（含未到期数据）。ctx 结束时返回 ctx.Err()。
+func (q *DelayQueue[E]) GetContext(ctx context.Context) (E, uint32, error) {
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	for {
+		ch := q.changed.wait()
+		q.mu.Lock()
+		wait := time.Duration(-1)
+		if q.heap.len() > 0 {
+			if wait = q.untilDue(); wait <= 0 {
+				val := q.heap.pop()
+				used := uint32(q.heap.len())
+				q.mu.Unlock()
+				return val, used, nil
+			}
+		}
+		q.mu.Unlock()
+
+		var due <-chan time.Time
+		if wait > 0 {
+			if timer == nil {
+				timer = time.NewTimer(wait)
+			} else {
+				timer.Reset(wait)
+			}
+			due = timer.C
+		}
+		select {
+		case <-ch:
+		case <-due:
+		case <-ctx.Done():
+			var empty E
+			return empty, 0, ctx.Err()
+		}
+		if timer != nil && !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+	}
+}
+
+// Cap 返回队列长度。
+func (q *DelayQueue[E]) Cap() uint32 {
+	return q.capacity
+}
+
+// Len 返回队列数据个数，含未到期数据。
+func (q *DelayQueue[E]) Len() uint32 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return uint32(q.heap.len())
+}
+
+// IsEmpty 判断队列是否有数据，含未到期数据。
+func (q *DelayQueue[E]) IsEmpty() bool {
+	return q.Len() == 0
+}
+
+// 距最早到期数据到期的时长，调用方须持有锁且队列非空。
+func (q *DelayQueue[E]) untilDue() time.Duration {
+	return time.Until(time.Unix(0, -q.heap.items[0].priority))
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestDelayQueue(t *testing.T) {
+	q := queue.NewDelayQueue[int](8)
+	q.PutAfter(2, time.Millisecond*40)
+	q.PutAfter(1, time.Millisecond*20)
+	q.PutAt(0, time.Now().Add(-time.Second))
+	if val, used, err := q.Get(); err != nil || val != 0 || used != 2 {
+		t.Fatal("due value not returned")
+	}
+	if _, _, err := q.Get(); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+
+	start := time.Now()
+	for want := 1; want <= 2; want++ {
+		val, _, err := q.GetContext(context.Background())
+		if err != nil || val != want {
+			t.Fatalf("val %d != %d", val, want)
+		}
+	}
+	if time.Since(start) < time.Millisecond*40 {
+		t.Fatal("returned before due")
+	}
+
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		q.PutAfter(3, 0)
+	}()
+	if val, _, err := q.GetContext(context.Background()); err != nil || val != 3 {
+		t.Fatal("blocked GetContext not woken by PutAfter")
+	}
+
+	q.PutAfter(4, time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if _, _, err := q.GetContext(ctx); err != context.DeadlineExceeded {
+		t.Fatal("err != DeadlineExceeded")
+	}
+	if q.Len() != 1 {
+		t.Fatal("Len != 1")
+	}
+}
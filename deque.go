@@ -0,0 +1,152 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import "sync/atomic"
+
+type (
+	// Deque 双端队列，两端均可填充和取出数据。使用 NewDeque 创建变量。
+	//
+	// 与 Queue 相同，先以 CAS 移动位置认领槽位，再按槽位序号等待该槽位可写入或可取出。头尾位置合并为一个64位整数，
+	// 使两端的认领互不冲突。同一槽位可能先后被两端认领，其序号依次经过空、写入中、有数据、取出中四个阶段，保证操作依次进行。
+	Deque[E any] struct {
+		capacity, mask uint32
+		_              [cacheLinePadSize - 8]byte
+		ends           uint64 // 高32位为头部位置，低32位为尾部位置。
+		_              [cacheLinePadSize - 8]byte
+		slots          []dequeSlot[E]
+	}
+	dequeSlot[E any] struct {
+		seq   uint32
+		value E
+	}
+)
+
+// NewDeque 创建双端队列。capacity 队列长度，调整规则同 New。
+func NewDeque[E any](capacity uint32) *Deque[E] {
+	capacity = roundCapacity(capacity)
+	return &Deque[E]{
+		capacity: capacity,
+		mask:     capacity - 1,
+		slots:    make([]dequeSlot[E], capacity),
+	}
+}
+
+// PutFront 向队列头部填充数据，该数据将最先被 GetFront 取出。返回剩余可填充数据个数。若队列已满返回错误 ErrQueueIsFull。
+func (q *Deque[E]) PutFront(value E) (uint32, error) {
+	return q.put(true, value)
+}
+
+// PutBack 向队列尾部填充数据。返回剩余可填充数据个数。若队列已满返回错误 ErrQueueIsFull。
+func (q *Deque[E]) PutBack(value E) (uint32, error) {
+	return q.put(false, value)
+}
+
+// GetFront 取出队列头部数据。返回队列数据，队列剩余可取个数。当无数据可取时返回错误 ErrQueueIsEmpty。
+func (q *Deque[E]) GetFront() (E, uint32, error) {
+	return q.get(true)
+}
+
+// GetBack 取出队列尾部数据。返回队列数据，队列剩余可取个数。当无数据可取时返回错误 ErrQueueIsEmpty。
+func (q *Deque[E]) GetBack() (E, uint32, error) {
+	return q.get(false)
+}
+
+// Cap 返回队列长度。
+func (q *Deque[E]) Cap() uint32 {
+	return q.capacity
+}
+
+// Len 返回队列数据个数。
+func (q *Deque[E]) Len() uint32 {
+	ends := atomic.LoadUint64(&q.ends)
+	return uint32(ends) - uint32(ends>>32)
+}
+
+// IsEmpty 判断队列是否有数据。
+func (q *Deque[E]) IsEmpty() bool {
+	return q.Len() == 0
+}
+
+// IsFull 判断队列是否已满。
+func (q *Deque[E]) IsFull() bool {
+	return q.Len() == q.capacity
+}
+
+func (q *Deque[E]) put(front bool, value E) (uint32, error) {
+	position, length, err := q.claim(front, true)
+	if err != nil {
+		return 0, err
+	}
+	slot := &q.slots[position&q.mask]
+	// 等待槽位为空，以 CAS 将序号推进到写入中。
+	for i := 0; ; i++ {
+		seq := atomic.LoadUint32(&slot.seq)
+		if seq&3 == 0 && atomic.CompareAndSwapUint32(&slot.seq, seq, seq+1) {
+			slot.value = value
+			atomic.StoreUint32(&slot.seq, seq+2)
+			return q.capacity - length, nil
+		}
+		defaultBackoff.Wait(i)
+	}
+}
+
+func (q *Deque[E]) get(front bool) (E, uint32, error) {
+	var empty E
+	position, length, err := q.claim(front, false)
+	if err != nil {
+		return empty, 0, err
+	}
+	slot := &q.slots[position&q.mask]
+	// 等待槽位有数据，以 CAS 将序号推进到取出中。
+	for i := 0; ; i++ {
+		seq := atomic.LoadUint32(&slot.seq)
+		if seq&3 == 2 && atomic.CompareAndSwapUint32(&slot.seq, seq, seq+1) {
+			val := slot.value
+			slot.value = empty
+			atomic.StoreUint32(&slot.seq, seq+2)
+			return val, length, nil
+		}
+		defaultBackoff.Wait(i)
+	}
+}
+
+// 以 CAS 移动头部或尾部位置认领一个槽位。front 是否操作头部，put 是否填充。返回认领的位置，认领后的数据个数。
+func (q *Deque[E]) claim(front, put bool) (uint32, uint32, error) {
+	for {
+		ends := atomic.LoadUint64(&q.ends)
+		head, tail := uint32(ends>>32), uint32(ends)
+		var position uint32
+		switch {
+		case put && tail-head == q.capacity:
+			return 0, 0, ErrQueueIsFull
+		case !put && tail == head:
+			return 0, 0, ErrQueueIsEmpty
+		case put && front:
+			head--
+			position = head
+		case put:
+			position = tail
+			tail++
+		case front:
+			position = head
+			head++
+		default:
+			tail--
+			position = tail
+		}
+		if atomic.CompareAndSwapUint64(&q.ends, ends, uint64(head)<<32|uint64(tail)) {
+			return position, tail - head, nil
+		}
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestDeque(t *testing.T) {
+	q := queue.NewDeque[int](4)
+	if _, _, err := q.GetFront(); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+	q.PutBack(2)
+	q.PutBack(3)
+	q.PutFront(1)
+	if left, _ := q.PutFront(0); left != 0 {
+		t.Fatal("left != 0")
+	}
+	if _, err := q.PutBack(4); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	if val, _, _ := q.GetFront(); val != 0 {
+		t.Fatal("val != 0")
+	}
+	if val, used, _ := q.GetBack(); val != 3 || used != 2 {
+		t.Fatal("GetBack mismatch")
+	}
+	if val, _, _ := q.GetBack(); val != 2 {
+		t.Fatal("val != 2")
+	}
+	if val, _, _ := q.GetFront(); val != 1 {
+		t.Fatal("val != 1")
+	}
+	if !q.IsEmpty() {
+		t.Fatal("queue not empty")
+	}
+}
+
+func TestDequeConcurrent(t *testing.T) {
+	const (
+		workers = 4
+		count   = 5000
+	)
+	q := queue.NewDeque[int](16)
+	var (
+		sum  int64
+		wg   sync.WaitGroup
+		seen [workers * count]int32
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < count; {
+				v := w*count + i
+				var err error
+				if i%2 == 0 {
+					_, err = q.PutFront(v)
+				} else {
+					_, err = q.PutBack(v)
+				}
+				if err == nil {
+					i++
+				} else {
+					runtime.Gosched()
+				}
+			}
+		}(w)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < count; {
+				var (
+					v   int
+					err error
+				)
+				if (w+i)%2 == 0 {
+					v, _, err = q.GetFront()
+				} else {
+					v, _, err = q.GetBack()
+				}
+				if err != nil {
+					runtime.Gosched()
+					continue
+				}
+				if atomic.AddInt32(&seen[v], 1) != 1 {
+					t.Error("value duplicated")
+				}
+				atomic.AddInt64(&sum, int64(v))
+				i++
+			}
+		}(w)
+	}
+	wg.Wait()
+	if n := int64(workers * count); sum != n*(n-1)/2 || !q.IsEmpty() {
+		t.Fatalf("sum %d mismatch", sum)
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"sync/atomic"
+)
+
+type (
+	// StateDump 队列内部状态快照，用于排查问题。使用 DumpState 获取。
+	StateDump[E any] struct {
+		Capacity, Mask, Head, Tail uint32
+		Slots                      []SlotState[E]
+	}
+	// SlotState 队列元素的状态。
+	SlotState[E any] struct {
+		GetSeq, PutSeq uint32
+		Value          E
+	}
+	// DumpOptions Dump 的输出选项。
+	DumpOptions[E any] struct {
+		// Values 是否输出有数据位置上的元素。
+		Values bool
+		// Format 格式化元素，为 nil 时使用 fmt.Sprint。
+		Format func(E) string
+		// OccupiedOnly 是否只输出有数据或正被操作的位置。
+		OccupiedOnly bool
+	}
+)
+
+// DumpState 返回队列内部状态快照，withValues 表示是否复制元素数据。
+//
+// 各字段依次读取，并发操作时快照并非同一时刻的状态。复制元素数据会与并发的填充、取出操作产生数据竞争，仅应在队列静止时开启。
+func (q *Queue[E]) DumpState(withValues bool) StateDump[E] {
+	dump := StateDump[E]{
+		Capacity: q.capacity,
+		Mask:     q.mask,
+		Head:     atomic.LoadUint32(&q.head),
+		Tail:     atomic.LoadUint32(&q.tail),
+		Slots:    make([]SlotState[E], q.capacity),
+	}
+	for i := range dump.Slots {
+		elem := q.element(uint32(i))
+		dump.Slots[i].GetSeq = atomic.LoadUint32(&elem.getSeq)
+		dump.Slots[i].PutSeq = atomic.LoadUint32(&elem.putSeq)
+		if withValues {
+			dump.Slots[i].Value = elem.value
+		}
+	}
+	return dump
+}
+
+// Dump 向 w 输出队列内部状态，包括头尾位置、各位置的占用图以及每个位置的 putSeq、getSeq 和状态，用于排查卡住的位置。
+//
+// 占用图中每个字符对应一个位置：'.' 空闲，'#' 有数据，'L' 正被取出或查看，'?' 序号不符合以上任何状态，通常意味着异常。
+// 读取方式同 DumpState，opts.Values 为 true 时与并发操作存在数据竞争，仅应在队列静止时开启。返回 w 的写入错误。
+func (q *Queue[E]) Dump(w io.Writer, opts DumpOptions[E]) error {
+	dump := q.DumpState(opts.Values)
+	states := make([]byte, len(dump.Slots))
+	for i, slot := range dump.Slots {
+		states[i] = slotStatus(slot.GetSeq, slot.PutSeq, dump.Capacity)
+	}
+	format := opts.Format
+	if format == nil {
+		format = func(v E) string { return fmt.Sprint(v) }
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, q.String())
+	fmt.Fprintf(bw, "Occupancy: %s\n", states)
+	for i, slot := range dump.Slots {
+		if opts.OccupiedOnly && states[i] == '.' {
+			continue
+		}
+		fmt.Fprintf(bw, "Slot %d: PutSeq:%d GetSeq:%d State:%c", i, slot.PutSeq, slot.GetSeq, states[i])
+		if opts.Values && states[i] == '#' {
+			fmt.Fprintf(bw, " Value:%s", format(slot.Value))
+		}
+		fmt.Fprintln(bw)
+	}
+	return bw.Flush()
+}
+
+// GoString 实现 fmt.GoStringer，输出队列名称、头尾位置、数据个数、容量以及按先进先出顺序的数据，数据复制方式同 Snapshot。
+func (q *Queue[E]) GoString() string {
+	return fmt.Sprintf("&safe_queue.Queue[%s]{Name:%q, Head:%d, Tail:%d, Len:%d, Cap:%d, Values:%#v}",
+		reflect.TypeOf((*E)(nil)).Elem(), q.opts.name, atomic.LoadUint32(&q.head), atomic.LoadUint32(&q.tail),
+		q.Len(), q.Cap(), q.Snapshot())
+}
+
+// 根据槽位序号判断其状态。
+func slotStatus(getSeq, putSeq, capacity uint32) byte {
+	switch {
+	case getSeq == putSeq:
+		return '.'
+	case putSeq-capacity == getSeq:
+		return '#'
+	case putSeq-capacity == getSeq+1:
+		return 'L'
+	default:
+		return '?'
+	}
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestDumpState(t *testing.T) {
+	q := queue.New[int](4)
+	q.PutEnough(10, 20, 30)
+	q.Get()
+
+	dump := q.DumpState(true)
+	if dump.Capacity != 4 || dump.Mask != 3 {
+		t.Fatal("Capacity != 4 || Mask != 3")
+	}
+	if q.String() != "Queue: Head:1 Tail:3 Len:2 Cap:4" || dump.Head != 1 || dump.Tail != 3 {
+		t.Fatal("head/tail mismatch")
+	}
+	if len(dump.Slots) != 4 {
+		t.Fatal("len(Slots) != 4")
+	}
+	expected := []queue.SlotState[int]{
+		{GetSeq: 4, PutSeq: 4},
+		{GetSeq: 5, PutSeq: 5},
+		{GetSeq: 2, PutSeq: 6, Value: 20},
+		{GetSeq: 3, PutSeq: 7, Value: 30},
+	}
+	for i, slot := range dump.Slots {
+		if slot != expected[i] {
+			t.Fatalf("slot %d %+v != %+v", i, slot, expected[i])
+		}
+	}
+
+	if dump = q.DumpState(false); dump.Slots[2].Value != 0 {
+		t.Fatal("value copied")
+	}
+}
+
+func TestDump(t *testing.T) {
+	q := queue.New[int](4, queue.WithName("jobs"))
+	q.PutEnough(10, 20, 30)
+	q.Get()
+
+	var buf bytes.Buffer
+	if err := q.Dump(&buf, queue.DumpOptions[int]{}); err != nil {
+		t.Fatal(err)
+	}
+	expected := `Queue(jobs): Head:1 Tail:3 Len:2 Cap:4
+Occupancy: ..##
+Slot 0: PutSeq:4 GetSeq:4 State:.
+Slot 1: PutSeq:5 GetSeq:5 State:.
+Slot 2: PutSeq:6 GetSeq:2 State:#
+Slot 3: PutSeq:7 GetSeq:3 State:#
+`
+	if buf.String() != expected {
+		t.Fatal("dump mismatch", buf.String())
+	}
+
+	buf.Reset()
+	format := func(v int) string { return "0x" + strconv.FormatInt(int64(v), 16) }
+	if err := q.Dump(&buf, queue.DumpOptions[int]{Values: true, Format: format, OccupiedOnly: true}); err != nil {
+		t.Fatal(err)
+	}
+	expected = `Queue(jobs): Head:1 Tail:3 Len:2 Cap:4
+Occupancy: ..##
+Slot 2: PutSeq:6 GetSeq:2 State:# Value:0x14
+Slot 3: PutSeq:7 GetSeq:3 State:# Value:0x1e
+`
+	if buf.String() != expected {
+		t.Fatal("dump mismatch", buf.String())
+	}
+
+	if s := fmt.Sprintf("%#v", q); s != `&safe_queue.Queue[int]{Name:"jobs", Head:1, Tail:3, Len:2, Cap:4, Values:[]int{20, 30}}` {
+		t.Fatal("go string mismatch", s)
+	}
+}
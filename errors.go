@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"strconv"
+	"sync/atomic"
+)
+
+// Locale 错误信息使用的语言。
+type Locale int32
+
+const (
+	// LocaleChinese 中文错误信息。默认使用。
+	LocaleChinese Locale = iota
+	// LocaleEnglish 英文错误信息。
+	LocaleEnglish
+)
+
+var errorLocale int32
+
+// SetErrorLocale 设置包内错误信息使用的语言，对已创建的错误同样生效。错误值本身不变，errors.Is 和 == 的比较不受影响。
+func SetErrorLocale(l Locale) {
+	atomic.StoreInt32(&errorLocale, int32(l))
+}
+
+// ErrorLocale 返回包内错误信息使用的语言。
+func ErrorLocale() Locale {
+	return Locale(atomic.LoadInt32(&errorLocale))
+}
+
+type localizedError struct {
+	zh, en string
+}
+
+func newError(zh, en string) error {
+	return &localizedError{zh: zh, en: en}
+}
+
+func (e *localizedError) Error() string {
+	return localize(e.zh, e.en)
+}
+
+// 按 SetErrorLocale 设置的语言选择文本。
+func localize(zh, en string) string {
+	if ErrorLocale() == LocaleEnglish {
+		return en
+	}
+	return zh
+}
+
+// FullError 队列已满错误，使用 WithDetailedErrors 创建的队列返回该错误。errors.Is 可与 ErrQueueIsFull 匹配。
+type FullError struct {
+	// Name 队列名称，即 WithName 设置的值。
+	Name string
+	// Capacity 返回错误时的队列容量。
+	Capacity uint32
+	// Requested 本次请求填充的数据个数，如 PutEnough 传入的数据个数。
+	Requested uint32
+}
+
+// EmptyError 队列为空错误，使用 WithDetailedErrors 创建的队列返回该错误。errors.Is 可与 ErrQueueIsEmpty 匹配。
+type EmptyError struct {
+	// Name 队列名称，即 WithName 设置的值。
+	Name string
+}
+
+func (e *FullError) Error() string {
+	capacity := strconv.FormatUint(uint64(e.Capacity), 10)
+	requested := strconv.FormatUint(uint64(e.Requested), 10)
+	if ErrorLocale() == LocaleEnglish {
+		return queueLabel(e.Name) + ErrQueueIsFull.Error() + ", capacity " + capacity + ", requested " + requested
+	}
+	return queueLabel(e.Name) + ErrQueueIsFull.Error() + "，容量" + capacity + "，请求" + requested
+}
+
+// Is 判断 target 是否为 ErrQueueIsFull。
+func (e *FullError) Is(target error) bool {
+	return target == ErrQueueIsFull
+}
+
+func (e *EmptyError) Error() string {
+	return queueLabel(e.Name) + ErrQueueIsEmpty.Error()
+}
+
+// Is 判断 target 是否为 ErrQueueIsEmpty。
+func (e *EmptyError) Is(target error) bool {
+	return target == ErrQueueIsEmpty
+}
+
+func queueLabel(name string) string {
+	if name == "" {
+		return ""
+	}
+	return name + ": "
+}
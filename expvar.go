@@ -0,0 +1,47 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"expvar"
+	"sync/atomic"
+)
+
+// PublishExpvar 以 name 为名将队列状态发布到 expvar，可通过 /debug/vars 查看。
+// 包含数据个数、容量、填充率、历史最高数据个数，使用 WithStats 创建的队列还包含统计数据。
+//
+// 每次读取时才访问队列状态，不影响队列操作。name 已被发布时将 panic。
+func (q *Queue[E]) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		length := q.Len()
+		vars := map[string]any{
+			"len":       length,
+			"cap":       q.Cap(),
+			"fillRatio": float64(length) / float64(q.Cap()),
+			"maxLen":    q.MaxLen(),
+		}
+		if q.counters != nil {
+			q.counters.export(vars)
+		}
+		return vars
+	}))
+}
+
+// 将统计数据写入 vars。
+func (c *counters) export(vars map[string]any) {
+	vars["puts"] = atomic.LoadUint64(&c.puts)
+	vars["gets"] = atomic.LoadUint64(&c.gets)
+	vars["casRetries"] = atomic.LoadUint64(&c.casRetries)
+	vars["fullFailures"] = atomic.LoadUint64(&c.fullFailures)
+	vars["emptyFailures"] = atomic.LoadUint64(&c.emptyFailures)
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+var expvarSeq uint32
+
+// 返回本次运行唯一的 expvar 名称，使 -count 大于一时不会重复发布。
+func expvarName(t *testing.T) string {
+	return t.Name() + "_" + strconv.FormatUint(uint64(atomic.AddUint32(&expvarSeq, 1)), 10)
+}
+
+func TestPublishExpvar(t *testing.T) {
+	q := queue.New[int](8, queue.WithStats())
+	name := expvarName(t)
+	q.PublishExpvar(name)
+	q.PutEnough(1, 2)
+	q.Get()
+
+	v := expvar.Get(name)
+	if v == nil {
+		t.Fatal("expvar not published")
+	}
+	var vars struct {
+		Len       uint32  `json:"len"`
+		Cap       uint32  `json:"cap"`
+		FillRatio float64 `json:"fillRatio"`
+		MaxLen    uint32  `json:"maxLen"`
+		Puts      uint64  `json:"puts"`
+		Gets      uint64  `json:"gets"`
+	}
+	if err := json.Unmarshal([]byte(v.String()), &vars); err != nil {
+		t.Fatal(err)
+	}
+	if vars.Len != 1 || vars.Cap != 8 || vars.FillRatio != 0.125 || vars.MaxLen != 2 {
+		t.Fatal(v.String())
+	}
+	if vars.Puts != 2 || vars.Gets != 1 {
+		t.Fatal(v.String())
+	}
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import "sync"
+
+// FixedArray 可作为 FixedQueue 存储的数组类型，长度为2的幂数，从2至65536。
+type FixedArray[E any] interface {
+	~[2]E | ~[4]E | ~[8]E | ~[16]E | ~[32]E | ~[64]E | ~[128]E | ~[256]E | ~[512]E | ~[1024]E |
+		~[2048]E | ~[4096]E | ~[8192]E | ~[16384]E | ~[32768]E | ~[65536]E
+}
+
+// FixedQueue 定长队列，数据直接保存在 A 类型的数组中，不另行分配内存，可直接嵌入其它结构体。零值即可使用，不可复制。
+//
+// 例如 FixedQueue[int, [64]int] 为长度64的队列。零值无法初始化 Queue 的槽位序号，内部使用互斥锁保护。
+type FixedQueue[E any, A FixedArray[E]] struct {
+	mu           sync.Mutex
+	head, length uint32
+	elements     A
+}
+
+// Put 向队列尾部填充数据。返回剩余可填充数据个数。若队列已满返回错误 ErrQueueIsFull。
+func (q *FixedQueue[E, A]) Put(value E) (uint32, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	capacity := q.Cap()
+	if q.length == capacity {
+		return 0, ErrQueueIsFull
+	}
+	q.elements[(q.head+q.length)&(capacity-1)] = value
+	q.length++
+	return capacity - q.length, nil
+}
+
+// Get 取出队列头部数据。返回队列数据，队列剩余可取个数。当无数据可取时返回错误 ErrQueueIsEmpty。
+func (q *FixedQueue[E, A]) Get() (E, uint32, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var empty E
+	if q.length == 0 {
+		return empty, 0, ErrQueueIsEmpty
+	}
+	index := q.head & (q.Cap() - 1)
+	val := q.elements[index]
+	q.elements[index] = empty
+	q.head++
+	q.length--
+	return val, q.length, nil
+}
+
+// Cap 返回队列长度。
+func (q *FixedQueue[E, A]) Cap() uint32 {
+	return uint32(len(q.elements))
+}
+
+// Len 返回队列数据个数。
+func (q *FixedQueue[E, A]) Len() uint32 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.length
+}
+
+// IsEmpty 判断队列是否有数据。
+func (q *FixedQueue[E, A]) IsEmpty() bool {
+	return q.Len() == 0
+}
+
+// IsFull 判断队列是否已满。
+func (q *FixedQueue[E, A]) IsFull() bool {
+	return q.Len() == q.Cap()
+}
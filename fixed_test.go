@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"unsafe"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestFixedQueue(t *testing.T) {
+	var s struct {
+		q queue.FixedQueue[int, [4]int]
+	}
+	if unsafe.Sizeof(s) < unsafe.Sizeof([4]int{}) {
+		t.Fatal("storage not inline")
+	}
+	q := &s.q
+	if q.Cap() != 4 || !q.IsEmpty() {
+		t.Fatal("cap != 4")
+	}
+	if _, _, err := q.Get(); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 3; j++ {
+			if _, err := q.Put(i*3 + j); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for j := 0; j < 3; j++ {
+			if val, _, _ := q.Get(); val != i*3+j {
+				t.Fatal("val mismatch")
+			}
+		}
+	}
+	for i := 0; i < 4; i++ {
+		q.Put(i)
+	}
+	if _, err := q.Put(4); err != queue.ErrQueueIsFull || !q.IsFull() {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+}
+
+func TestFixedQueueConcurrent(t *testing.T) {
+	var q queue.FixedQueue[int, [16]int]
+	const count = 10000
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < count; {
+			if _, err := q.Put(i); err != nil {
+				runtime.Gosched()
+				continue
+			}
+			i++
+		}
+	}()
+	for i := 0; i < count; {
+		val, _, err := q.Get()
+		if err != nil {
+			runtime.Gosched()
+			continue
+		}
+		if val != i {
+			t.Fatal("val mismatch")
+		}
+		i++
+	}
+	wg.Wait()
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"context"
+	"time"
+)
+
+// MetricsSink 接收 Instrumented 记录的操作数据。
+//
+// op 为方法名，如 "Put"、"GetEnough"；n 为实际填充或取出的数据个数；d 为操作耗时；err 为操作返回的错误。
+// 每次操作都将同步调用 Observe，实现应尽快返回且可被并发调用。
+type MetricsSink interface {
+	Observe(op string, n uint32, d time.Duration, err error)
+}
+
+// MetricsSinkFunc 将函数适配为 MetricsSink。
+type MetricsSinkFunc func(op string, n uint32, d time.Duration, err error)
+
+// Observe 调用 f。
+func (f MetricsSinkFunc) Observe(op string, n uint32, d time.Duration, err error) {
+	f(op, n, d, err)
+}
+
+// Instrumented 记录每次操作的数据个数、耗时和错误并交给 MetricsSink 的队列装饰器。使用 Instrument 创建变量。
+type Instrumented[E any] struct {
+	q    Interface[E]
+	sink MetricsSink
+}
+
+// InstrumentedBlocking 同 Instrumented，并记录阻塞等待和关闭操作。使用 InstrumentBlocking 创建变量。
+type InstrumentedBlocking[E any] struct {
+	*Instrumented[E]
+	q BlockingInterface[E]
+}
+
+// Instrument 包装 q，使其每次操作都记录到 sink。
+func Instrument[E any](q Interface[E], sink MetricsSink) *Instrumented[E] {
+	return &Instrumented[E]{q: q, sink: sink}
+}
+
+// InstrumentBlocking 包装 q，使其每次操作都记录到 sink，包括阻塞等待和关闭操作。
+func InstrumentBlocking[E any](q BlockingInterface[E], sink MetricsSink) *InstrumentedBlocking[E] {
+	return &InstrumentedBlocking[E]{Instrumented: Instrument[E](q, sink), q: q}
+}
+
+// Put 同 Queue.Put。
+func (i *Instrumented[E]) Put(value E) (uint32, error) {
+	start := time.Now()
+	left, err := i.q.Put(value)
+	i.observe("Put", countOf(err), start, err)
+	return left, err
+}
+
+// Get 同 Queue.Get。
+func (i *Instrumented[E]) Get() (E, uint32, error) {
+	start := time.Now()
+	val, used, err := i.q.Get()
+	i.observe("Get", countOf(err), start, err)
+	return val, used, err
+}
+
+// PutEnough 同 Queue.PutEnough。
+func (i *Instrumented[E]) PutEnough(values ...E) (uint32, uint32) {
+	start := time.Now()
+	n, left := i.q.PutEnough(values...)
+	i.observe("PutEnough", n, start, nil)
+	return n, left
+}
+
+// GetEnough 同 Queue.GetEnough。
+func (i *Instrumented[E]) GetEnough(size uint32) ([]E, uint32, uint32) {
+	start := time.Now()
+	values, n, used := i.q.GetEnough(size)
+	i.observe("GetEnough", n, start, nil)
+	return values, n, used
+}
+
+// Cap 同 Queue.Cap，不记录。
+func (i *Instrumented[E]) Cap() uint32 {
+	return i.q.Cap()
+}
+
+// Len 同 Queue.Len，不记录。
+func (i *Instrumented[E]) Len() uint32 {
+	return i.q.Len()
+}
+
+// IsEmpty 同 Queue.IsEmpty，不记录。
+func (i *Instrumented[E]) IsEmpty() bool {
+	return i.q.IsEmpty()
+}
+
+// IsFull 同 Queue.IsFull，不记录。
+func (i *Instrumented[E]) IsFull() bool {
+	return i.q.IsFull()
+}
+
+// PutContext 同 Queue.PutContext，耗时包括等待时间。
+func (i *InstrumentedBlocking[E]) PutContext(ctx context.Context, value E) (uint32, error) {
+	start := time.Now()
+	left, err := i.q.PutContext(ctx, value)
+	i.observe("PutContext", countOf(err), start, err)
+	return left, err
+}
+
+// GetContext 同 Queue.GetContext，耗时包括等待时间。
+func (i *InstrumentedBlocking[E]) GetContext(ctx context.Context) (E, uint32, error) {
+	start := time.Now()
+	val, used, err := i.q.GetContext(ctx)
+	i.observe("GetContext", countOf(err), start, err)
+	return val, used, err
+}
+
+// Close 同 Queue.Close。
+func (i *InstrumentedBlocking[E]) Close() {
+	start := time.Now()
+	i.q.Close()
+	i.observe("Close", 0, start, nil)
+}
+
+func (i *Instrumented[E]) observe(op string, n uint32, start time.Time, err error) {
+	i.sink.Observe(op, n, time.Since(start), err)
+}
+
+// 单个数据操作成功时返回1，否则返回0。
+func countOf(err error) uint32 {
+	if err != nil {
+		return 0
+	}
+	return 1
+}
+
+var (
+	_ Interface[int]         = (*Instrumented[int])(nil)
+	_ BlockingInterface[int] = (*InstrumentedBlocking[int])(nil)
+)
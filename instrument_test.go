@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestInstrument(t *testing.T) {
+	var mu sync.Mutex
+	counts := make(map[string]uint32)
+	errs := make(map[string]int)
+	sink := queue.MetricsSinkFunc(func(op string, n uint32, d time.Duration, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		counts[op] += n
+		if err != nil {
+			errs[op]++
+		}
+		if d < 0 {
+			t.Error("d < 0")
+		}
+	})
+
+	q := queue.Instrument[int](queue.NewSpsc[int](2), sink)
+	q.Put(1)
+	q.PutEnough(2, 3)
+	if _, err := q.Put(4); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	q.GetEnough(2)
+	q.Get()
+	if counts["Put"] != 1 || counts["PutEnough"] != 1 || counts["GetEnough"] != 2 || errs["Put"] != 1 || errs["Get"] != 1 {
+		t.Fatal(counts, errs)
+	}
+
+	b := queue.InstrumentBlocking[int](queue.New[int](2), sink)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if _, _, err := b.GetContext(ctx); err != context.DeadlineExceeded {
+		t.Fatal("err != DeadlineExceeded")
+	}
+	b.PutContext(context.Background(), 5)
+	b.Close()
+	if counts["PutContext"] != 1 || errs["GetContext"] != 1 {
+		t.Fatal(counts, errs)
+	}
+}
+
+func TestWithMetricsSink(t *testing.T) {
+	counts := make(map[string]uint32)
+	errs := make(map[string]int)
+	sink := queue.MetricsSinkFunc(func(op string, n uint32, d time.Duration, err error) {
+		counts[op] += n
+		if err != nil {
+			errs[op]++
+		}
+	})
+
+	q := queue.New[int](2, queue.WithMetricsSink(sink))
+	q.Put(1)
+	q.PutEnough(2, 3)
+	if _, err := q.Put(4); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	q.GetEnough(2)
+	q.Get()
+	if counts["Put"] != 1 || counts["PutEnough"] != 1 || counts["GetEnough"] != 2 || errs["Put"] != 1 || errs["Get"] != 1 {
+		t.Fatal(counts, errs)
+	}
+	q.Len()
+	if len(counts) != 4 {
+		t.Fatal(counts)
+	}
+}
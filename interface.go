@@ -0,0 +1,43 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import "context"
+
+// Interface 有界队列的基本操作，由 Queue、BlockingQueue、SpscQueue 实现。应用代码依赖该接口即可按需切换实现。
+//
+// 各方法的语义同 Queue 的同名方法。SpscQueue 仅允许一个协程填充、一个协程取出。
+type Interface[E any] interface {
+	Put(value E) (uint32, error)
+	Get() (E, uint32, error)
+	PutEnough(values ...E) (uint32, uint32)
+	GetEnough(size uint32) ([]E, uint32, uint32)
+	Cap() uint32
+	Len() uint32
+	IsEmpty() bool
+	IsFull() bool
+}
+
+// BlockingInterface 在 Interface 基础上增加阻塞等待和关闭操作，由 Queue、BlockingQueue 实现。
+type BlockingInterface[E any] interface {
+	Interface[E]
+	PutContext(ctx context.Context, value E) (uint32, error)
+	GetContext(ctx context.Context) (E, uint32, error)
+	Close()
+}
+
+var (
+	_ BlockingInterface[int] = (*Queue[int])(nil)
+	_ BlockingInterface[int] = (*BlockingQueue[int])(nil)
+	_ Interface[int]         = (*SpscQueue[int])(nil)
+)
@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"context"
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestInterface(t *testing.T) {
+	for _, q := range []queue.BlockingInterface[int]{queue.New[int](4), queue.NewBlocking[int](4)} {
+		if q.Cap() != 4 || !q.IsEmpty() {
+			t.Fatal("cap != 4")
+		}
+		q.PutEnough(1, 2)
+		if _, err := q.PutContext(context.Background(), 3); err != nil {
+			t.Fatal(err)
+		}
+		if val, _, _ := q.Get(); val != 1 {
+			t.Fatal("val != 1")
+		}
+		q.Close()
+		if _, err := q.Put(4); err != queue.ErrQueueIsClosed {
+			t.Fatal("err != ErrQueueIsClosed")
+		}
+		if values, n, _ := q.GetEnough(4); n != 2 || values[1] != 3 {
+			t.Fatal("values != [2 3]")
+		}
+		if _, _, err := q.GetContext(context.Background()); err != queue.ErrQueueIsClosed {
+			t.Fatal("err != ErrQueueIsClosed")
+		}
+	}
+}
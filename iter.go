@@ -0,0 +1,63 @@
+//go:build go1.23
+
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"context"
+	"iter"
+)
+
+// All 返回遍历队列当前所有数据的迭代器，不取出数据。遍历开始时按 Snapshot 复制数据，遍历期间队列的变化不影响遍历。
+func (q *Queue[E]) All() iter.Seq[E] {
+	return func(yield func(E) bool) {
+		for _, v := range q.Snapshot() {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// Consume 返回逐个取出数据的迭代器，无数据时等待。ctx 结束，或队列已关闭且数据取尽时结束遍历。
+//
+//	for v := range q.Consume(ctx) {
+//		...
+//	}
+//
+// 提前退出遍历时，已取出的数据不会放回队列。
+func (q *Queue[E]) Consume(ctx context.Context) iter.Seq[E] {
+	return func(yield func(E) bool) {
+		for {
+			v, _, err := q.GetContext(ctx)
+			if err != nil || !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// PutSeq 将 seq 中的数据逐个填充到队列尾部，队列已满时等待。返回填充数据个数。
+// seq 遍历结束时返回 nil；ctx 结束或填充失败时停止遍历，返回的错误同 PutContext，此时正在填充的数据被丢弃。
+func (q *Queue[E]) PutSeq(ctx context.Context, seq iter.Seq[E]) (uint32, error) {
+	var n uint32
+	var err error
+	for v := range seq {
+		if _, err = q.PutContext(ctx, v); err != nil {
+			break
+		}
+		n++
+	}
+	return n, err
+}
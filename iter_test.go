@@ -0,0 +1,103 @@
+//go:build go1.23
+
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestAll(t *testing.T) {
+	q := queue.New[int](4)
+	q.PutEnough(1, 2, 3)
+	sum := 0
+	for v := range q.All() {
+		sum += v
+		if v == 2 {
+			break
+		}
+	}
+	if sum != 3 || q.Len() != 3 {
+		t.Fatal("sum != 3")
+	}
+}
+
+func TestConsume(t *testing.T) {
+	q := queue.New[int](4)
+	go func() {
+		for i := 1; i <= 10; i++ {
+			q.MustPut(i)
+		}
+		q.Close()
+	}()
+	sum := 0
+	for v := range q.Consume(context.Background()) {
+		sum += v
+	}
+	if sum != 55 {
+		t.Fatal("sum != 55")
+	}
+
+	q = queue.New[int](4)
+	q.PutEnough(1, 2, 3)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	n := 0
+	for v := range q.Consume(ctx) {
+		n++
+		if v == 2 {
+			break
+		}
+	}
+	if n != 2 || q.Len() != 1 {
+		t.Fatal("n != 2")
+	}
+	for range q.Consume(ctx) {
+		n++
+	}
+	if n != 3 || ctx.Err() == nil {
+		t.Fatal("n != 3")
+	}
+}
+
+func TestPutSeq(t *testing.T) {
+	q := queue.New[int](4)
+	seq := func(yield func(int) bool) {
+		for i := 1; i <= 6; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		q.GetEnough(2)
+	}()
+	if n, err := q.PutSeq(context.Background(), seq); err != nil || n != 6 {
+		t.Fatal("n != 6")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if n, err := q.PutSeq(ctx, seq); err != context.DeadlineExceeded || n != 0 {
+		t.Fatal("err != DeadlineExceeded")
+	}
+	q.Close()
+	if _, err := q.PutSeq(context.Background(), seq); err != queue.ErrQueueIsClosed {
+		t.Fatal("err != ErrQueueIsClosed")
+	}
+}
@@ -0,0 +1,245 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"sync/atomic"
+)
+
+var (
+	// ErrNoElementCodec 表明队列未使用 WithElementCodec 设置元素编解码器，无法恢复数据。
+	ErrNoElementCodec = newError("未设置元素编解码器", "no element codec")
+	// ErrInvalidState 表明待恢复的队列状态数据无效。
+	ErrInvalidState = newError("队列状态数据无效", "invalid queue state")
+)
+
+// 二进制状态数据的版本号。
+const stateVersion = 1
+
+type (
+	// ElementCodec 元素编解码器，用于序列化队列数据。使用 WithElementCodec 设置。
+	ElementCodec[E any] interface {
+		Encode(value E) ([]byte, error)
+		Decode(data []byte) (E, error)
+	}
+
+	// JSONCodec 使用 encoding/json 编解码元素。
+	JSONCodec[E any] struct{}
+
+	// 队列状态的 JSON 格式。
+	queueState struct {
+		Capacity uint32   `json:"capacity"`
+		Head     uint32   `json:"head"`
+		Tail     uint32   `json:"tail"`
+		Len      uint32   `json:"len"`
+		Elements [][]byte `json:"elements,omitempty"`
+	}
+)
+
+// Encode 编码元素。
+func (JSONCodec[E]) Encode(value E) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+// Decode 解码元素。
+func (JSONCodec[E]) Decode(data []byte) (E, error) {
+	var value E
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
+
+// MarshalJSON 实现 json.Marshaler，输出队列容量、头尾位置和数据个数。
+// 设置了 WithElementCodec 时，按先进先出顺序输出编码后的数据，每个数据为 base64 字符串。
+//
+// 各字段依次读取，数据复制方式同 Snapshot，并发操作时结果并非同一时刻的状态。
+func (q *Queue[E]) MarshalJSON() ([]byte, error) {
+	state, err := q.state()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(state)
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler，清空队列后按顺序填充 MarshalJSON 输出的数据。
+//
+// 队列须已使用 New 创建并设置 WithElementCodec，否则返回 ErrNoElementCodec。
+// 使用 WithResizable 或 WithAutoGrow 创建的队列在 Resize 的锁内一次性替换数据，并恢复保存的容量；
+// 其余队列不可调整容量，保存的容量仅用于校验，且调用方须保证恢复期间没有其他协程操作队列，否则数据可能被并发的填充打乱。
+// 头尾位置是内部状态，不恢复，恢复后从零开始。
+//
+// 队列已关闭时返回 ErrQueueIsClosed，已暂停时返回 ErrQueuePaused，数据个数或总权重超过上限时返回 ErrQueueIsFull，
+// 数据个数超过保存的容量时返回 ErrInvalidState。出错时队列数据不变。
+func (q *Queue[E]) UnmarshalJSON(data []byte) error {
+	var state queueState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	return q.restore(&state)
+}
+
+// MarshalBinary 实现 encoding.BinaryMarshaler，内容同 MarshalJSON。
+func (q *Queue[E]) MarshalBinary() ([]byte, error) {
+	state, err := q.state()
+	if err != nil {
+		return nil, err
+	}
+	size := 1 + 4*5
+	for _, v := range state.Elements {
+		size += 4 + len(v)
+	}
+	data := make([]byte, size)
+	data[0] = stateVersion
+	binary.LittleEndian.PutUint32(data[1:], state.Capacity)
+	binary.LittleEndian.PutUint32(data[5:], state.Head)
+	binary.LittleEndian.PutUint32(data[9:], state.Tail)
+	binary.LittleEndian.PutUint32(data[13:], state.Len)
+	binary.LittleEndian.PutUint32(data[17:], uint32(len(state.Elements)))
+	index := 21
+	for _, v := range state.Elements {
+		binary.LittleEndian.PutUint32(data[index:], uint32(len(v)))
+		index += 4 + copy(data[index+4:], v)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary 实现 encoding.BinaryUnmarshaler，恢复方式同 UnmarshalJSON。数据格式错误时返回 ErrInvalidState。
+func (q *Queue[E]) UnmarshalBinary(data []byte) error {
+	if len(data) < 1+4*5 || data[0] != stateVersion {
+		return ErrInvalidState
+	}
+	state := queueState{
+		Capacity: binary.LittleEndian.Uint32(data[1:]),
+		Head:     binary.LittleEndian.Uint32(data[5:]),
+		Tail:     binary.LittleEndian.Uint32(data[9:]),
+		Len:      binary.LittleEndian.Uint32(data[13:]),
+	}
+	count := binary.LittleEndian.Uint32(data[17:])
+	data = data[21:]
+	if uint64(count)*4 > uint64(len(data)) {
+		return ErrInvalidState
+	}
+	state.Elements = make([][]byte, count)
+	for i := range state.Elements {
+		if len(data) < 4 {
+			return ErrInvalidState
+		}
+		n := binary.LittleEndian.Uint32(data)
+		if uint64(n) > uint64(len(data)-4) {
+			return ErrInvalidState
+		}
+		state.Elements[i], data = data[4:4+n], data[4+n:]
+	}
+	if len(data) != 0 {
+		return ErrInvalidState
+	}
+	return q.restore(&state)
+}
+
+// 读取队列状态，设置了编解码器时编码数据。
+func (q *Queue[E]) state() (*queueState, error) {
+	state := &queueState{
+		Capacity: q.Cap(),
+		Head:     atomic.LoadUint32(&q.head),
+		Tail:     atomic.LoadUint32(&q.tail),
+	}
+	state.Len = state.Tail - state.Head
+	if q.codec == nil {
+		return state, nil
+	}
+	values := q.Snapshot()
+	state.Len = uint32(len(values))
+	state.Elements = make([][]byte, len(values))
+	for i, v := range values {
+		data, err := q.codec.Encode(v)
+		if err != nil {
+			return nil, err
+		}
+		state.Elements[i] = data
+	}
+	return state, nil
+}
+
+// 解码全部数据后清空队列并填充。
+func (q *Queue[E]) restore(state *queueState) error {
+	if q.codec == nil {
+		return ErrNoElementCodec
+	}
+	if state.Len != uint32(len(state.Elements)) || state.Capacity > 0 && state.Len > state.Capacity {
+		return ErrInvalidState
+	}
+	values := make([]E, len(state.Elements))
+	for i, data := range state.Elements {
+		v, err := q.codec.Decode(data)
+		if err != nil {
+			return err
+		}
+		values[i] = v
+	}
+
+	if q.gate == nil {
+		if err := q.checkRestore(values, q.Cap()); err != nil {
+			return err
+		}
+		q.Clear()
+		return q.fullError(q.PutAtomic(values...), state.Len)
+	}
+
+	q.gate.Lock()
+	capacity, limit := q.capacity, q.Cap()
+	if state.Capacity > 0 {
+		capacity, limit = roundCapacity(state.Capacity), q.limitOf(state.Capacity)
+	}
+	if err := q.checkRestore(values, limit); err != nil {
+		q.gate.Unlock()
+		return err
+	}
+	q.rebuildWhere(capacity, func(E) bool { return true })
+	q.place(values)
+	if q.weightOf != nil {
+		for _, v := range values {
+			atomic.AddUint32(&q.weight, q.weightOf(v))
+		}
+	}
+	atomic.StoreUint32(&q.limit, limit)
+	atomic.StoreUint32(&q.tail, uint32(len(values)))
+	q.gate.Unlock()
+
+	q.notEmpty.notify()
+	q.notFull.notify()
+	return nil
+}
+
+// 检查队列能否容纳待恢复的数据。limit 恢复后的容量。
+func (q *Queue[E]) checkRestore(values []E, limit uint32) error {
+	if q.isClosed() {
+		return ErrQueueIsClosed
+	}
+	if q.isPaused() {
+		return ErrQueuePaused
+	}
+	if uint32(len(values)) > limit {
+		return q.fullError(q.errFull, uint32(len(values)))
+	}
+	if q.weightOf != nil {
+		var sum uint64
+		for _, v := range values {
+			sum += uint64(q.weightOf(v))
+		}
+		if sum > uint64(q.maxWeight) {
+			return q.fullError(q.errFull, uint32(len(values)))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	q := queue.New[string](4)
+	q.PutEnough("a", "b")
+	data, err := json.Marshal(q)
+	if err != nil || string(data) != `{"capacity":4,"head":0,"tail":2,"len":2}` {
+		t.Fatal("json mismatch", string(data))
+	}
+	if err = json.Unmarshal(data, q); err != queue.ErrNoElementCodec {
+		t.Fatal("err != ErrNoElementCodec")
+	}
+
+	codec := queue.WithElementCodec[string](queue.JSONCodec[string]{})
+	q = queue.New[string](4, codec)
+	q.PutEnough("x", "a", "b")
+	q.Get()
+	if data, err = json.Marshal(q); err != nil || !strings.Contains(string(data), `"len":2,"elements":["ImEi","ImIi"]`) {
+		t.Fatal("json mismatch", string(data))
+	}
+	restored := queue.New[string](4, codec)
+	restored.Put("z")
+	if err = json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	if values := restored.Snapshot(); len(values) != 2 || values[0] != "a" || values[1] != "b" {
+		t.Fatal("values != [a b]")
+	}
+
+	small := queue.New[string](2, codec)
+	small.Put("z")
+	q.PutEnough("c", "d")
+	data, _ = json.Marshal(q)
+	if err = json.Unmarshal(data, small); err != queue.ErrQueueIsFull || small.Len() != 1 {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	if err = json.Unmarshal([]byte(`{"len":1}`), small); err != queue.ErrInvalidState {
+		t.Fatal("err != ErrInvalidState")
+	}
+	if err = json.Unmarshal([]byte(`{"capacity":1,"len":2,"elements":["ImEi","ImIi"]}`), small); err != queue.ErrInvalidState {
+		t.Fatal("err != ErrInvalidState")
+	}
+}
+
+func TestUnmarshalKeepsData(t *testing.T) {
+	codec := queue.WithElementCodec[int](queue.JSONCodec[int]{})
+	src := queue.New[int](8, codec)
+	src.PutEnough(1, 2, 3, 4)
+	data, _ := src.MarshalBinary()
+
+	q := queue.New[int](8, codec)
+	q.Put(9)
+	q.Pause()
+	if err := q.UnmarshalBinary(data); err != queue.ErrQueuePaused || q.Len() != 1 {
+		t.Fatal("err != ErrQueuePaused")
+	}
+	q.Resume()
+	q.Close()
+	if err := q.UnmarshalBinary(data); err != queue.ErrQueueIsClosed || q.Len() != 1 {
+		t.Fatal("err != ErrQueueIsClosed")
+	}
+
+	q = queue.New[int](8, codec, queue.WithWeights(func(v int) uint32 { return uint32(v) }, 9))
+	q.Put(9)
+	if err := q.UnmarshalBinary(data); err != queue.ErrQueueIsFull || q.Len() != 1 || q.Weight() != 9 {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+
+	q = queue.New[int](2, codec, queue.WithResizable(), queue.WithWeights(func(v int) uint32 { return uint32(v) }, 20))
+	q.Put(9)
+	if err := q.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if q.Cap() != 8 || q.Weight() != 10 {
+		t.Fatal("capacity or weight not restored")
+	}
+	if vals, _, _ := q.GetEnough(8); len(vals) != 4 || vals[0] != 1 || vals[3] != 4 || q.Weight() != 0 {
+		t.Fatal("vals != [1 2 3 4]")
+	}
+	if _, err := q.Put(5); err != nil {
+		t.Fatal(err)
+	}
+	if val, _, _ := q.Get(); val != 5 {
+		t.Fatal("val != 5")
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	codec := queue.WithElementCodec[int](queue.JSONCodec[int]{})
+	q := queue.New[int](8, codec)
+	q.PutEnough(1, 2, 3)
+	data, err := q.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := queue.New[int](8, codec)
+	if err = restored.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if values := restored.Snapshot(); len(values) != 3 || values[0] != 1 || values[2] != 3 {
+		t.Fatal("values != [1 2 3]")
+	}
+	if err = restored.UnmarshalBinary(data[:len(data)-1]); err != queue.ErrInvalidState || restored.Len() != 3 {
+		t.Fatal("err != ErrInvalidState")
+	}
+	if err = restored.UnmarshalBinary(nil); err != queue.ErrInvalidState {
+		t.Fatal("err != ErrInvalidState")
+	}
+
+	if data, err = queue.New[int](8).MarshalBinary(); err != nil || len(data) != 21 {
+		t.Fatal("len(data) != 21")
+	}
+}
@@ -0,0 +1,211 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"math"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// 填充失败次数占填充尝试次数的比例达到该值时，认为队列经常已满。
+	fullFailureThreshold = 0.01
+	// 平均每次操作的 CAS 重试次数达到该值时，认为队列竞争激烈。
+	contentionThreshold = 0.1
+	maxCapacity         = 1 << 31
+)
+
+type counters struct {
+	puts, gets, casRetries, fullFailures, emptyFailures uint64
+}
+
+// QueueStats 队列状态快照。使用 Stats 获取。
+type QueueStats struct {
+	// Len、Free、Cap、Head、Tail 取自同一时刻，Len 与 Free 之和等于 Cap。
+	Len, Free, Cap, Head, Tail uint32
+	// MaxLen 数据个数的历史最高值。
+	MaxLen         uint32
+	Closed, Paused bool
+	// 以下统计数据仅在使用 WithStats 创建的队列中有效，各自独立读取。
+	Puts, Gets, CASRetries, FullFailures, EmptyFailures uint64
+}
+
+// Stats 一次性返回队列状态快照，避免分别调用 Len、Cap、IsFull 等得到相互矛盾的结果，适合监控和流量控制。
+//
+// 头尾位置在两次读取间未被取出操作改变时才采用，保证二者及据此计算的 Len、Free 对应同一时刻；其余字段依次读取。
+func (q *Queue[E]) Stats() QueueStats {
+	var stats QueueStats
+	q.enter()
+	for {
+		head := atomic.LoadUint32(&q.head)
+		tail := atomic.LoadUint32(&q.tail)
+		if head == atomic.LoadUint32(&q.head) {
+			stats.Head, stats.Tail = head, tail
+			break
+		}
+		runtime.Gosched()
+	}
+	stats.Cap = q.Cap()
+	q.leave()
+
+	stats.Len = q.usedSize(stats.Tail, stats.Head)
+	if stats.Len > stats.Cap {
+		stats.Len = stats.Cap
+	}
+	stats.Free = stats.Cap - stats.Len
+	stats.MaxLen = q.MaxLen()
+	stats.Closed = q.isClosed()
+	stats.Paused = q.isPaused()
+	if q.counters != nil {
+		stats.Puts = atomic.LoadUint64(&q.counters.puts)
+		stats.Gets = atomic.LoadUint64(&q.counters.gets)
+		stats.CASRetries = atomic.LoadUint64(&q.counters.casRetries)
+		stats.FullFailures = atomic.LoadUint64(&q.counters.fullFailures)
+		stats.EmptyFailures = atomic.LoadUint64(&q.counters.emptyFailures)
+	}
+	return stats
+}
+
+// SuggestCapacity 根据运行数据给出建议的队列容量：
+//
+//   - 经常已满时建议加倍，若同时竞争激烈则建议扩为四倍；
+//   - 数据个数历史最高值不足容量四分之一时，建议缩减为最高值两倍对应的 2 的幂数；
+//   - 其它情况维持现有容量。
+//
+// 是否已满及竞争情况依赖 WithStats 的统计数据，未开启时仅以历史最高值是否达到容量判断是否已满。
+func (q *Queue[E]) SuggestCapacity() uint32 {
+	capacity := q.Cap()
+	full, contended := q.MaxLen() == capacity, false
+	if q.counters != nil {
+		puts := atomic.LoadUint64(&q.counters.puts)
+		gets := atomic.LoadUint64(&q.counters.gets)
+		fullFailures := atomic.LoadUint64(&q.counters.fullFailures)
+		casRetries := atomic.LoadUint64(&q.counters.casRetries)
+		full = fullFailures > 0 && float64(fullFailures)/float64(puts+fullFailures) >= fullFailureThreshold
+		contended = puts+gets > 0 && float64(casRetries)/float64(puts+gets) >= contentionThreshold
+	}
+
+	switch maxLen := q.MaxLen(); {
+	case full && contended:
+		return uint32(minUint64(uint64(capacity)*4, maxCapacity))
+	case full:
+		return uint32(minUint64(uint64(capacity)*2, maxCapacity))
+	case maxLen < capacity/4:
+		suggestion := uint32(2)
+		for suggestion < maxLen*2 {
+			suggestion <<= 1
+		}
+		return suggestion
+	default:
+		return capacity
+	}
+}
+
+func minUint64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func (q *Queue[E]) observe() {
+	ticker := time.NewTicker(q.opts.observeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.done:
+			return
+		case <-ticker.C:
+			q.opts.observer(atomic.LoadUint32(&q.head), atomic.LoadUint32(&q.tail))
+		}
+	}
+}
+
+// MaxLen 返回队列数据个数的历史最高值。
+func (q *Queue[E]) MaxLen() uint32 {
+	return atomic.LoadUint32(&q.maxLen)
+}
+
+// MaxLenAndReset 返回队列数据个数的历史最高值，并将其重置为当前数据个数。适合按周期上报峰值。
+func (q *Queue[E]) MaxLenAndReset() uint32 {
+	return atomic.SwapUint32(&q.maxLen, q.Len())
+}
+
+func (q *Queue[E]) updateMaxLen(used uint32) {
+	for {
+		old := atomic.LoadUint32(&q.maxLen)
+		if used <= old || atomic.CompareAndSwapUint32(&q.maxLen, old, used) {
+			return
+		}
+	}
+}
+
+// PutLabeled 同 Put，id 为调用方标识。使用 WithGoroutineAttribution 创建队列时，将按 id 统计成功次数。
+func (q *Queue[E]) PutLabeled(id int, value E) (uint32, error) {
+	left, err := q.Put(value)
+	if err == nil {
+		q.attribute(id)
+	}
+	return left, err
+}
+
+// GetLabeled 同 Get，id 为调用方标识。使用 WithGoroutineAttribution 创建队列时，将按 id 统计成功次数。
+func (q *Queue[E]) GetLabeled(id int) (E, uint32, error) {
+	val, used, err := q.Get()
+	if err == nil {
+		q.attribute(id)
+	}
+	return val, used, err
+}
+
+// AttributionReport 返回各调用方标识成功操作的次数。未使用 WithGoroutineAttribution 时返回 nil。
+func (q *Queue[E]) AttributionReport() map[int]uint64 {
+	if q.attribution == nil {
+		return nil
+	}
+	report := make(map[int]uint64)
+	q.attribution.Range(func(key, value any) bool {
+		report[key.(int)] = atomic.LoadUint64(value.(*uint64))
+		return true
+	})
+	return report
+}
+
+func (q *Queue[E]) attribute(id int) {
+	if q.attribution == nil {
+		return
+	}
+	counter, ok := q.attribution.Load(id)
+	if !ok {
+		counter, _ = q.attribution.LoadOrStore(id, new(uint64))
+	}
+	atomic.AddUint64(counter.(*uint64), 1)
+}
+
+// FillEWMA 返回队列填充率的指数加权移动平均值，取值范围 [0, 1]。未使用 WithFillEWMA 时恒为零。
+func (q *Queue[E]) FillEWMA() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&q.fillEWMA))
+}
+
+func (q *Queue[E]) updateFillEWMA(used uint32) {
+	ratio := float64(used) / float64(q.limit)
+	for {
+		old := atomic.LoadUint64(&q.fillEWMA)
+		ewma := q.ewmaAlpha*ratio + (1-q.ewmaAlpha)*math.Float64frombits(old)
+		if atomic.CompareAndSwapUint64(&q.fillEWMA, old, math.Float64bits(ewma)) {
+			return
+		}
+	}
+}
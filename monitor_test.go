@@ -0,0 +1,248 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestPositionObserver(t *testing.T) {
+	type sample struct{ head, tail uint32 }
+	var (
+		mu      sync.Mutex
+		samples []sample
+	)
+	q := queue.New[int](8, queue.WithPositionObserver(func(head, tail uint32) {
+		mu.Lock()
+		samples = append(samples, sample{head, tail})
+		mu.Unlock()
+	}, time.Millisecond))
+
+	for i := 0; i < 20; i++ {
+		q.MustPut(i)
+		q.MustPut(i)
+		q.MustGet()
+		q.MustGet()
+		time.Sleep(time.Millisecond * 2)
+	}
+	q.Close()
+	time.Sleep(time.Millisecond * 5)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(samples) < 3 {
+		t.Fatalf("samples %d < 3", len(samples))
+	}
+	for i, s := range samples {
+		if s.tail < s.head {
+			t.Fatal("tail < head")
+		}
+		if i > 0 && (s.head < samples[i-1].head || s.tail < samples[i-1].tail) {
+			t.Fatal("position moved backward")
+		}
+	}
+	last := samples[len(samples)-1]
+	if last.head == 0 && last.tail == 0 {
+		t.Fatal("no movement observed")
+	}
+}
+
+func TestMaxLenAndReset(t *testing.T) {
+	q := queue.New[int](8)
+	q.PutEnough(1, 2, 3, 4, 5, 6)
+	q.GetEnough(4)
+	if q.MaxLen() != 6 {
+		t.Fatal("MaxLen != 6")
+	}
+	if peak := q.MaxLenAndReset(); peak != 6 {
+		t.Fatal("peak != 6")
+	}
+	if q.MaxLen() != 2 {
+		t.Fatal("MaxLen != 2")
+	}
+
+	q.PutEnough(7, 8)
+	q.GetEnough(3)
+	if peak := q.MaxLenAndReset(); peak != 4 {
+		t.Fatal("peak != 4")
+	}
+	if peak := q.MaxLenAndReset(); peak != 1 {
+		t.Fatal("peak != 1")
+	}
+}
+
+func TestGoroutineAttribution(t *testing.T) {
+	const (
+		producers = 4
+		count     = 50
+	)
+	q := queue.New[int](producers*count, queue.WithGoroutineAttribution())
+	wg := sync.WaitGroup{}
+	for i := 0; i < producers; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < count*(id+1)/producers; j++ {
+				if _, err := q.PutLabeled(id, j); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	report := q.AttributionReport()
+	var total uint64
+	for id, n := range report {
+		if n != uint64(count*(id+1)/producers) {
+			t.Fatalf("report[%d] = %d", id, n)
+		}
+		total += n
+	}
+	if total != uint64(q.Len()) {
+		t.Fatal("total != Len")
+	}
+
+	for q.Len() > 0 {
+		q.GetLabeled(producers)
+	}
+	if q.AttributionReport()[producers] != total {
+		t.Fatal("consumer count != total")
+	}
+
+	if queue.New[int](2).AttributionReport() != nil {
+		t.Fatal("AttributionReport != nil")
+	}
+}
+
+func TestFillEWMA(t *testing.T) {
+	q := queue.New[int](8, queue.WithFillEWMA(0.2))
+	if q.FillEWMA() != 0 {
+		t.Fatal("FillEWMA != 0")
+	}
+	q.PutEnough(1, 2, 3, 4, 5, 6)
+	for i := 0; i < 50; i++ {
+		q.MustGet()
+		q.MustPut(i)
+	}
+	if ewma := q.FillEWMA(); ewma < 0.625 || ewma > 0.75 {
+		t.Fatalf("ewma %f not in [0.625, 0.75]", ewma)
+	}
+	q.GetEnough(4)
+	for i := 0; i < 50; i++ {
+		q.MustPut(i)
+		q.MustGet()
+	}
+	if ewma := q.FillEWMA(); ewma < 0.25 || ewma > 0.375 {
+		t.Fatalf("ewma %f not in [0.25, 0.375]", ewma)
+	}
+
+	if queue.New[int](2).FillEWMA() != 0 {
+		t.Fatal("FillEWMA != 0")
+	}
+}
+
+func TestSuggestCapacity(t *testing.T) {
+	const producers = 16
+	q := queue.New[int](8, queue.WithStats())
+	wg := sync.WaitGroup{}
+	for i := 0; i < producers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				q.Put(j)
+				if j%4 == 0 {
+					q.Get()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if suggestion := q.SuggestCapacity(); suggestion <= q.Cap() {
+		t.Fatalf("suggestion %d <= Cap", suggestion)
+	}
+
+	q = queue.New[int](64, queue.WithStats())
+	for i := 0; i < 100; i++ {
+		q.PutEnough(1, 2, 3)
+		q.GetEnough(3)
+	}
+	if suggestion := q.SuggestCapacity(); suggestion != 8 {
+		t.Fatalf("suggestion %d != 8", suggestion)
+	}
+
+	q = queue.New[int](16)
+	q.PutEnough(make([]int, 10)...)
+	if suggestion := q.SuggestCapacity(); suggestion != 16 {
+		t.Fatalf("suggestion %d != 16", suggestion)
+	}
+}
+
+func TestStats(t *testing.T) {
+	q := queue.New[int](4, queue.WithStats())
+	q.PutEnough(1, 2, 3)
+	q.Get()
+	q.Get()
+	q.Get()
+	q.Get()
+	q.Put(4)
+	stats := q.Stats()
+	expected := queue.QueueStats{Len: 1, Free: 3, Cap: 4, Head: 3, Tail: 4, MaxLen: 3, Puts: 4, Gets: 3, EmptyFailures: 1}
+	if stats != expected {
+		t.Fatalf("stats %+v != %+v", stats, expected)
+	}
+	q.Close()
+	if !q.Stats().Closed {
+		t.Fatal("closed != true")
+	}
+
+	q = queue.New[int](8)
+	const count = 10000
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < count; i++ {
+			q.MustPut(i)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < count; i++ {
+			q.MustGet()
+		}
+	}()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		stats = q.Stats()
+		if stats.Len+stats.Free != stats.Cap || stats.Tail-stats.Head != stats.Len {
+			t.Fatalf("torn stats %+v", stats)
+		}
+		runtime.Gosched()
+	}
+}
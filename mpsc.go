@@ -0,0 +1,134 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+type (
+	// MpscQueue 多生产者单消费者队列。使用 NewMpsc 创建变量。
+	//
+	// 多个协程可同时填充数据，只允许一个协程取出数据。生产者竞争尾部位置，消费者独占头部位置，无需 CAS。
+	// 违反单消费者约定将导致数据错乱。
+	MpscQueue[E any] struct {
+		capacity, mask uint32
+		_              [cacheLinePadSize - 8]byte
+		head           uint32
+		_              [cacheLinePadSize - 4]byte
+		tail           uint32
+		_              [cacheLinePadSize - 4]byte
+		slots          []seqSlot[E]
+	}
+
+	// 带序号的槽位。seq 等于位置时可填充，等于位置加一时可取出。
+	seqSlot[E any] struct {
+		seq   uint32
+		value E
+	}
+)
+
+// NewMpsc 创建多生产者单消费者队列。capacity 队列长度，调整规则同 New。
+func NewMpsc[E any](capacity uint32) *MpscQueue[E] {
+	capacity = roundCapacity(capacity)
+	q := &MpscQueue[E]{
+		capacity: capacity,
+		mask:     capacity - 1,
+		slots:    make([]seqSlot[E], capacity),
+	}
+	for i := range q.slots {
+		q.slots[i].seq = uint32(i)
+	}
+	return q
+}
+
+// Put 向队列尾部填充数据。返回剩余可填充数据个数。若队列已满返回错误 ErrQueueIsFull。
+func (q *MpscQueue[E]) Put(value E) (uint32, error) {
+	for {
+		tail := atomic.LoadUint32(&q.tail)
+		slot := &q.slots[tail&q.mask]
+		switch dif := int32(atomic.LoadUint32(&slot.seq) - tail); {
+		case dif == 0:
+			if atomic.CompareAndSwapUint32(&q.tail, tail, tail+1) {
+				slot.value = value
+				atomic.StoreUint32(&slot.seq, tail+1)
+				return q.capacity - (tail + 1 - atomic.LoadUint32(&q.head)), nil
+			}
+		case dif < 0:
+			return 0, ErrQueueIsFull
+		default:
+			runtime.Gosched()
+		}
+	}
+}
+
+// Get 取出队列头部数据。返回队列数据，队列剩余可取个数。当无数据可取时返回错误 ErrQueueIsEmpty。仅允许消费者调用。
+//
+// 生产者已占用位置但尚未写完数据时，视为无数据。
+func (q *MpscQueue[E]) Get() (E, uint32, error) {
+	head := q.head
+	slot := &q.slots[head&q.mask]
+	if atomic.LoadUint32(&slot.seq) != head+1 {
+		var empty E
+		return empty, 0, ErrQueueIsEmpty
+	}
+	val := slot.value
+	var empty E
+	slot.value = empty
+	atomic.StoreUint32(&slot.seq, head+q.capacity)
+	atomic.StoreUint32(&q.head, head+1)
+	return val, atomic.LoadUint32(&q.tail) - head - 1, nil
+}
+
+// DrainEach 按先进先出顺序取出已写完的连续数据，逐个调用 fn。返回取出数据个数。仅允许消费者调用。
+//
+// 只在结束时更新一次头部位置，不分配结果切片，适合事件循环批量处理。
+func (q *MpscQueue[E]) DrainEach(fn func(E)) uint32 {
+	head := q.head
+	var empty E
+	var n uint32
+	for ; n < q.capacity; n++ {
+		slot := &q.slots[(head+n)&q.mask]
+		if atomic.LoadUint32(&slot.seq) != head+n+1 {
+			break
+		}
+		val := slot.value
+		slot.value = empty
+		atomic.StoreUint32(&slot.seq, head+n+q.capacity)
+		fn(val)
+	}
+	atomic.StoreUint32(&q.head, head+n)
+	return n
+}
+
+// Cap 返回队列长度。
+func (q *MpscQueue[E]) Cap() uint32 {
+	return q.capacity
+}
+
+// Len 返回队列数据个数，包含生产者已占用但尚未写完的位置。
+func (q *MpscQueue[E]) Len() uint32 {
+	head := atomic.LoadUint32(&q.head)
+	return atomic.LoadUint32(&q.tail) - head
+}
+
+// IsEmpty 判断队列是否有数据。
+func (q *MpscQueue[E]) IsEmpty() bool {
+	return q.Len() == 0
+}
+
+// IsFull 判断队列是否已满。
+func (q *MpscQueue[E]) IsFull() bool {
+	return q.Len() == q.capacity
+}
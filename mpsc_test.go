@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestMpscQueue(t *testing.T) {
+	q := queue.NewMpsc[int](4)
+	if _, _, err := q.Get(); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+	for i := 1; i <= 4; i++ {
+		if _, err := q.Put(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := q.Put(5); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	if val, used, _ := q.Get(); val != 1 || used != 3 {
+		t.Fatal("Get mismatch")
+	}
+	var got []int
+	if n := q.DrainEach(func(v int) { got = append(got, v) }); n != 3 {
+		t.Fatal("n != 3")
+	}
+	if len(got) != 3 || got[0] != 2 || got[2] != 4 || !q.IsEmpty() {
+		t.Fatal("DrainEach mismatch")
+	}
+}
+
+func TestMpscQueueConcurrent(t *testing.T) {
+	const (
+		producers = 8
+		count     = 10000
+	)
+	q := queue.NewMpsc[int](64)
+	wg := sync.WaitGroup{}
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < count; {
+				if _, err := q.Put(p*count + i); err == nil {
+					i++
+				} else {
+					runtime.Gosched()
+				}
+			}
+		}(p)
+	}
+	last := make([]int, producers)
+	for i := range last {
+		last[i] = -1
+	}
+	received := 0
+	for received < producers*count {
+		n := q.DrainEach(func(v int) {
+			p, i := v/count, v%count
+			if i <= last[p] {
+				t.Errorf("producer %d out of order", p)
+			}
+			last[p] = i
+		})
+		if n == 0 {
+			runtime.Gosched()
+		}
+		received += int(n)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import "context"
+
+// MultiLaneQueue 多通道队列。数据放入指定通道，取出时按权重轮转选取通道，低权重通道也能按比例得到处理而不会饿死。
+// 使用 NewMultiLane 创建变量。
+//
+// 适合交互请求与批处理任务混合的服务。通道内先进先出，通道之间不保证顺序。
+type MultiLaneQueue[E any] struct {
+	lanes []*Queue[E]
+	mux   *Mux[E]
+}
+
+// NewMultiLane 创建多通道队列。capacity 每个通道的长度，调整规则同 New。weights 各通道的权重，其长度即通道个数，规则同 NewMux。
+// opts 同 New，作用于每个通道。
+func NewMultiLane[E any](capacity uint32, weights []int, opts ...Option) *MultiLaneQueue[E] {
+	q := &MultiLaneQueue[E]{lanes: make([]*Queue[E], len(weights))}
+	for i := range q.lanes {
+		q.lanes[i] = New[E](capacity, opts...)
+	}
+	q.mux = NewMux(q.lanes, weights)
+	return q
+}
+
+// Put 将数据放入第 lane 个通道。返回该通道剩余可填充数据个数。若该通道已满返回错误 ErrQueueIsFull。
+func (q *MultiLaneQueue[E]) Put(lane int, value E) (uint32, error) {
+	return q.lanes[lane].Put(value)
+}
+
+// Get 按权重选取通道取出一个数据。返回数据，所在通道剩余可取个数。所有通道均无数据时返回错误 ErrQueueIsEmpty。
+func (q *MultiLaneQueue[E]) Get() (E, uint32, error) {
+	return q.mux.Get()
+}
+
+// GetEnough 按权重选取通道取出至多 size 个数据。返回数据，实际取出数据个数，所有通道剩余可取数据个数。
+func (q *MultiLaneQueue[E]) GetEnough(size uint32) ([]E, uint32, uint32) {
+	return q.mux.GetEnough(size)
+}
+
+// GetContext 按权重选取通道取出一个数据，所有通道均无数据时等待，直至 ctx 结束。返回数据，所在通道剩余可取个数。
+// ctx 结束时返回 ctx.Err()，队列已关闭且无数据时返回 ErrQueueIsClosed。
+func (q *MultiLaneQueue[E]) GetContext(ctx context.Context) (E, uint32, error) {
+	return q.mux.GetContext(ctx)
+}
+
+// Lane 返回第 lane 个通道。
+func (q *MultiLaneQueue[E]) Lane(lane int) *Queue[E] {
+	return q.lanes[lane]
+}
+
+// Len 返回所有通道的数据个数之和。
+func (q *MultiLaneQueue[E]) Len() uint32 {
+	return q.mux.Len()
+}
+
+// Close 关闭所有通道。
+func (q *MultiLaneQueue[E]) Close() {
+	for _, lane := range q.lanes {
+		lane.Close()
+	}
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"context"
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestMultiLaneQueue(t *testing.T) {
+	const (
+		interactive = 0
+		batch       = 1
+	)
+	q := queue.NewMultiLane[int](128, []int{4, 1})
+	for i := 0; i < 100; i++ {
+		q.Put(interactive, interactive)
+		q.Put(batch, batch)
+	}
+	counts := make([]int, 2)
+	for i := 0; i < 50; i++ {
+		val, _, err := q.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[val]++
+	}
+	if counts[interactive] != 40 || counts[batch] != 10 {
+		t.Fatalf("counts %v not weighted 4:1", counts)
+	}
+	if q.Len() != 150 || q.Lane(batch).Len() != 90 {
+		t.Fatal("Len mismatch")
+	}
+	q.Close()
+	for {
+		if _, _, err := q.GetContext(context.Background()); err != nil {
+			if err != queue.ErrQueueIsClosed {
+				t.Fatal(err)
+			}
+			break
+		}
+	}
+}
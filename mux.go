@@ -0,0 +1,132 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+)
+
+// Mux 多路合并器。从多个队列公平地取出数据，对外表现为一个队列。使用 NewMux 创建变量。
+//
+// 按权重轮转选取起始队列，起始队列无数据时依次尝试其它队列。阻塞取出时同时等待所有队列，不轮询。
+type Mux[E any] struct {
+	next     uint32
+	sources  []*Queue[E]
+	schedule []int // 按权重展开的队列下标，轮转使用。
+}
+
+// NewMux 创建多路合并器。sources 数据来源，weights 各来源的权重，为 nil 时权重均为一，否则长度须与 sources 相同。
+// 权重为 n 的来源在每轮中被优先选取 n 次，权重小于一视为一。sources 为空或 weights 长度不符时 panic。
+func NewMux[E any](sources []*Queue[E], weights []int) *Mux[E] {
+	if len(sources) == 0 {
+		panic("sources is empty")
+	}
+	if weights != nil && len(weights) != len(sources) {
+		panic("len(weights) != len(sources)")
+	}
+	m := &Mux[E]{sources: sources}
+	// 交错展开，避免同一来源连续被选取。
+	for round := 1; ; round++ {
+		added := false
+		for i := range sources {
+			w := 1
+			if weights != nil && weights[i] > 1 {
+				w = weights[i]
+			}
+			if round <= w {
+				m.schedule = append(m.schedule, i)
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	return m
+}
+
+// Get 取出一个数据。返回数据，所在来源剩余可取个数。所有来源均无数据时返回错误 ErrQueueIsEmpty，
+// 所有来源均已关闭且无数据时返回 ErrQueueIsClosed。
+func (m *Mux[E]) Get() (E, uint32, error) {
+	start := int(atomic.AddUint32(&m.next, 1) % uint32(len(m.schedule)))
+	closed := 0
+	for i := range m.schedule {
+		val, used, err := m.sources[m.schedule[(start+i)%len(m.schedule)]].Get()
+		if err == nil {
+			return val, used, nil
+		}
+		if err == ErrQueueIsClosed {
+			closed++
+		}
+	}
+	var empty E
+	if closed == len(m.schedule) {
+		return empty, 0, ErrQueueIsClosed
+	}
+	return empty, 0, ErrQueueIsEmpty
+}
+
+// GetEnough 取出至多 size 个数据，每个数据按 Get 的规则选取来源。返回数据，实际取出数据个数，所有来源剩余可取数据个数。
+func (m *Mux[E]) GetEnough(size uint32) ([]E, uint32, uint32) {
+	capacity := size
+	if n := m.Len(); capacity > n {
+		capacity = n
+	}
+	res := make([]E, 0, capacity)
+	for uint32(len(res)) < size {
+		val, _, err := m.Get()
+		if err != nil {
+			break
+		}
+		res = append(res, val)
+	}
+	return res, uint32(len(res)), m.Len()
+}
+
+// GetContext 取出一个数据，所有来源均无数据时等待，直至 ctx 结束。返回数据，所在来源剩余可取个数。
+// ctx 结束时返回 ctx.Err()，所有来源均已关闭且无数据时返回 ErrQueueIsClosed。
+func (m *Mux[E]) GetContext(ctx context.Context) (E, uint32, error) {
+	cases := make([]reflect.SelectCase, 0, len(m.sources)+1)
+	for {
+		val, used, err := m.Get()
+		if err != ErrQueueIsEmpty {
+			return val, used, err
+		}
+		cases = append(cases[:0], reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())})
+		for _, q := range m.sources {
+			// 已关闭且无数据的来源的通道始终关闭，不参与等待。
+			if q.isClosed() && q.IsEmpty() {
+				continue
+			}
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(q.NotEmpty())})
+		}
+		if len(cases) == 1 {
+			continue
+		}
+		if chosen, _, _ := reflect.Select(cases); chosen == 0 {
+			var empty E
+			return empty, 0, ctx.Err()
+		}
+	}
+}
+
+// Len 返回所有来源的数据个数之和。
+func (m *Mux[E]) Len() uint32 {
+	var n uint32
+	for _, q := range m.sources {
+		n += q.Len()
+	}
+	return n
+}
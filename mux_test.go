@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestMux(t *testing.T) {
+	a, b := queue.New[string](64), queue.New[string](64)
+	for i := 0; i < 20; i++ {
+		a.Put("a")
+		b.Put("b")
+	}
+	m := queue.NewMux([]*queue.Queue[string]{a, b}, []int{3, 1})
+	vals, n, _ := m.GetEnough(16)
+	if n != 16 {
+		t.Fatal("n != 16")
+	}
+	counts := map[string]int{}
+	for _, v := range vals {
+		counts[v]++
+	}
+	if counts["a"] != 12 || counts["b"] != 4 {
+		t.Fatalf("counts %v not weighted 3:1", counts)
+	}
+
+	if vals, n, _ = m.GetEnough(math.MaxUint32); n != 24 || cap(vals) > 64 {
+		t.Fatal("GetEnough over-allocated")
+	}
+	if vals, _, _ = m.GetEnough(math.MaxUint32); len(vals) != 0 || cap(vals) != 0 {
+		t.Fatal("GetEnough over-allocated")
+	}
+	if _, _, err := m.Get(); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		b.Put("late")
+	}()
+	if val, _, err := m.GetContext(context.Background()); err != nil || val != "late" {
+		t.Fatal("GetContext not woken")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if _, _, err := m.GetContext(ctx); err != context.DeadlineExceeded {
+		t.Fatal("err != DeadlineExceeded")
+	}
+	a.Close()
+	b.Close()
+	if _, _, err := m.GetContext(context.Background()); err != queue.ErrQueueIsClosed {
+		t.Fatal("err != ErrQueueIsClosed")
+	}
+}
+
+func TestNewMuxInvalid(t *testing.T) {
+	for _, fn := range []func(){
+		func() { queue.NewMux[int](nil, nil) },
+		func() { queue.NewMux([]*queue.Queue[int]{queue.New[int](2)}, []int{1, 2}) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("invalid arguments not panic")
+				}
+			}()
+			fn()
+		}()
+	}
+	m := queue.NewMux([]*queue.Queue[int]{queue.New[int](2)}, []int{0})
+	if _, _, err := m.Get(); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+}
@@ -0,0 +1,271 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import "time"
+
+// Option 队列配置项。传递给 New 使用。
+type Option func(*options)
+
+// FullPolicy 队列已满时 Put 的处理方式。
+type FullPolicy int
+
+const (
+	// FullReject 返回 ErrQueueIsFull。默认使用。
+	FullReject FullPolicy = iota
+	// FullDropNewest 丢弃本次填充的数据，并返回成功。
+	FullDropNewest
+	// FullDropOldest 淘汰头部最旧的数据以腾出空位。
+	FullDropOldest
+	// FullBlock 等待队列出现空位，同 MustPut，但不 panic 而是返回错误。
+	FullBlock
+)
+
+// PaddingMode 队列元素数组的填充方式。
+type PaddingMode int
+
+const (
+	// PadSlots 每个元素至少独占一个缓存行，避免相邻位置的生产者与消费者互相干扰。默认使用。
+	PadSlots PaddingMode = iota
+	// PadNone 元素紧密排列，内存占用最小，但相邻位置可能共享缓存行。适合大容量或单生产者单消费者的队列。
+	PadNone
+)
+
+type options struct {
+	observer        func(head, tail uint32)
+	observeInterval time.Duration
+	weightOf        any
+	maxWeight       uint32
+	consumeLog      bool
+	attribution     bool
+	ewmaAlpha       float64
+	errFull         error
+	errEmpty        error
+	stats           bool
+	singleProducer  bool
+	singleConsumer  bool
+	onViolation     func(error)
+	strategy        WaitStrategy
+	backend         *Backend
+	blockWhenPaused bool
+	fullPolicy      FullPolicy
+	onDrop          any
+	exactCapacity   bool
+	resizable       bool
+	growMax         uint32
+	shrinkAfter     time.Duration
+	segmentSize     uint32
+	name            string
+	noZeroing       bool
+	detailedErrors  bool
+	codec           any
+	padding         PaddingMode
+	sink            MetricsSink
+}
+
+// WithPositionObserver 定时采样队列头尾位置，并回调 fn。interval 采样间隔，小于等于零时使用一秒。
+//
+// 回调由后台协程按间隔触发，只反映采样时刻的位置，并不会记录每次操作。调用 Close 后停止采样。
+func WithPositionObserver(fn func(head, tail uint32), interval time.Duration) Option {
+	return func(o *options) {
+		if interval <= 0 {
+			interval = time.Second
+		}
+		o.observer = fn
+		o.observeInterval = interval
+	}
+}
+
+// WithWeights 为元素设置权重。weightOf 计算元素权重，maxWeight 队列可容纳的总权重。
+//
+// 填充数据将使总权重超过 maxWeight 时返回 ErrQueueIsFull，而不论是否还有空位。取出数据后释放其权重。
+// weightOf 的元素类型须与队列元素类型一致，否则 New 将 panic，NewE 返回 ErrOptionType。
+func WithWeights[E any](weightOf func(E) uint32, maxWeight uint32) Option {
+	return func(o *options) {
+		if weightOf != nil {
+			o.weightOf = weightOf
+		}
+		o.maxWeight = maxWeight
+	}
+}
+
+// WithConsumeLog 记录每个被取出数据的序号，供测试使用 ConsumeLog 和 VerifyConsumeLog 校验是否重复消费。
+// 未开启时无额外开销。
+func WithConsumeLog() Option {
+	return func(o *options) {
+		o.consumeLog = true
+	}
+}
+
+// WithGoroutineAttribution 按调用方标识统计 PutLabeled 和 GetLabeled 成功操作的次数，使用 AttributionReport 查看。
+func WithGoroutineAttribution() Option {
+	return func(o *options) {
+		o.attribution = true
+	}
+}
+
+// WithFillEWMA 在每次填充和取出后，以指数加权移动平均的方式更新队列填充率，使用 FillEWMA 查看。
+//
+// alpha 为最新一次填充率所占权重，取值范围 (0, 1]，越大对变化越敏感，越小越平滑。大于 1 时按 1 处理。
+func WithFillEWMA(alpha float64) Option {
+	return func(o *options) {
+		o.ewmaAlpha = alpha
+	}
+}
+
+// WithErrors 设置队列已满和队列为空时返回的错误，便于区分多个队列。为 nil 的参数仍使用 ErrQueueIsFull 或 ErrQueueIsEmpty。
+//
+// 若需 errors.Is 能匹配包内错误，可使用 fmt.Errorf 的 %w 包装它们。
+func WithErrors(full, empty error) Option {
+	return func(o *options) {
+		o.errFull = full
+		o.errEmpty = empty
+	}
+}
+
+// WithStats 统计填充、取出、CAS 重试以及队列满、空导致的失败次数。统计在每次操作时进行原子计数，有少量开销。
+func WithStats() Option {
+	return func(o *options) {
+		o.stats = true
+	}
+}
+
+// WithCallerTracking 记录填充和取出数据的协程，用于调试单生产者或单消费者的使用约定。
+//
+// singleProducer、singleConsumer 表示只允许一个协程填充、取出数据。观察到第二个协程时调用 onViolation，
+// 参数为 ErrMultipleProducers 或 ErrMultipleConsumers。onViolation 为 nil 时直接 panic。
+// 获取协程标识的开销较大，仅应在调试时开启。
+func WithCallerTracking(singleProducer, singleConsumer bool, onViolation func(error)) Option {
+	return func(o *options) {
+		o.singleProducer = singleProducer
+		o.singleConsumer = singleConsumer
+		o.onViolation = onViolation
+	}
+}
+
+// WithWaitStrategy 设置等待策略，默认为 BackoffWait{Spins: 8, Yields: 16, MinSleep: time.Microsecond, MaxSleep: time.Millisecond}。使用 BlockingWait 时阻塞操作将挂起协程，等同于 ParkBackend。
+func WithWaitStrategy(s WaitStrategy) Option {
+	return func(o *options) {
+		o.strategy = s
+	}
+}
+
+// WithBlockingBackend 设置阻塞操作的等待方式，默认为 ParkBackend。运行时可使用 SetBlockingBackend 切换。
+func WithBlockingBackend(b Backend) Option {
+	return func(o *options) {
+		o.backend = &b
+	}
+}
+
+// WithBlockWhenPaused 使 PutContext、MustPut 等阻塞填充在队列暂停期间等待至 Resume，而不是返回 ErrQueuePaused。
+func WithBlockWhenPaused() Option {
+	return func(o *options) {
+		o.blockWhenPaused = true
+	}
+}
+
+// WithFullPolicy 设置队列已满时 Put 的处理方式，默认为 FullReject。onDrop 数据被丢弃或淘汰时回调，可用于统计，可为 nil。
+//
+// 仅影响 Put，PutEnough、PutAtomic 等批量填充不受影响。onDrop 的元素类型须与队列元素类型一致，否则 New 将 panic，NewE 返回 ErrOptionType。
+func WithFullPolicy[E any](policy FullPolicy, onDrop func(E)) Option {
+	return func(o *options) {
+		o.fullPolicy = policy
+		if onDrop != nil {
+			o.onDrop = onDrop
+		}
+	}
+}
+
+// WithExactCapacity 使队列按 New 指定的 capacity 限制数据个数，而不是调整后的2的幂数，Cap 也返回指定值。
+// 内部数组长度仍为调整后的值。capacity 为零时不生效。
+func WithExactCapacity() Option {
+	return func(o *options) {
+		o.exactCapacity = true
+	}
+}
+
+// WithResizable 允许运行时使用 Resize 调整队列容量。开启后每次填充和取出需额外获取读锁，有少量开销。
+func WithResizable() Option {
+	return func(o *options) {
+		o.resizable = true
+	}
+}
+
+// WithAutoGrow 队列已满时自动将容量加倍，直至 maxCapacity，调整规则同 New。仅影响 Put、PutContext 等单个填充。
+//
+// shrinkAfter 大于零时，若取出数据后数据个数持续低于容量四分之一超过 shrinkAfter，则将容量减半，不低于创建时的容量。
+// 调整容量的方式同 Resize，开启后同样有 WithResizable 的开销。
+func WithAutoGrow(maxCapacity uint32, shrinkAfter time.Duration) Option {
+	return func(o *options) {
+		o.growMax = maxCapacity
+		o.shrinkAfter = shrinkAfter
+	}
+}
+
+// WithSegmentSize 将元素数组按 size 个元素一段分段分配，避免大容量队列一次性分配巨大的连续内存。size 调整规则同 New。
+//
+// 队列容量不大于 size 时不分段。分段后每次访问元素需多一次下标计算。
+func WithSegmentSize(size uint32) Option {
+	return func(o *options) {
+		o.segmentSize = size
+	}
+}
+
+// WithName 设置队列名称，用于 String 等输出，便于区分多个队列。
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// WithoutZeroing 取出数据后不清零其所在位置，可减少一次写入。
+//
+// 已取出的数据在位置被重新填充前仍被队列引用，元素包含指针时将延迟其回收，仅建议用于不含指针的元素类型。
+func WithoutZeroing() Option {
+	return func(o *options) {
+		o.noZeroing = true
+	}
+}
+
+// WithDetailedErrors 队列已满和队列为空时返回 *FullError 和 *EmptyError，携带队列名称和容量，便于诊断。
+// 仍可使用 errors.Is 与 ErrQueueIsFull、ErrQueueIsEmpty 匹配。WithErrors 设置的错误优先。
+func WithDetailedErrors() Option {
+	return func(o *options) {
+		o.detailedErrors = true
+	}
+}
+
+// WithElementCodec 设置元素编解码器，用于 MarshalJSON、MarshalBinary 输出数据以及 UnmarshalJSON、UnmarshalBinary 恢复数据。
+// codec 的元素类型须与队列元素类型一致，否则 New 将 panic，NewE 返回 ErrOptionType。
+func WithElementCodec[E any](codec ElementCodec[E]) Option {
+	return func(o *options) {
+		if codec != nil {
+			o.codec = codec
+		}
+	}
+}
+
+// WithPadding 设置元素数组的填充方式，默认 PadSlots。
+func WithPadding(mode PaddingMode) Option {
+	return func(o *options) {
+		o.padding = mode
+	}
+}
+
+// WithMetricsSink 将 Put、Get、PutEnough、GetEnough 的数据个数、耗时和错误交给 sink，语义同 Instrument，但无需包装队列。
+// 其余方法不记录。
+func WithMetricsSink(sink MetricsSink) Option {
+	return func(o *options) {
+		o.sink = sink
+	}
+}
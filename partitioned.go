@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+// PartitionedQueue 分区队列组。按键的哈希值将数据放入固定的分区，同一键的数据保持先进先出，不同键分散到多个分区并行处理。
+// 使用 NewPartitioned 创建变量。
+//
+// 每个分区是一个独立的 Queue，通常为每个分区安排一个消费者，以保证同一键的数据按顺序处理。
+type PartitionedQueue[K comparable, E any] struct {
+	hash       func(K) uint64
+	partitions []*Queue[E]
+}
+
+// NewPartitioned 创建分区队列组。partitions 分区个数，capacity 每个分区的长度，hash 计算键的哈希值，opts 同 New，作用于每个分区。
+func NewPartitioned[K comparable, E any](partitions int, capacity uint32, hash func(K) uint64, opts ...Option) *PartitionedQueue[K, E] {
+	q := &PartitionedQueue[K, E]{
+		hash:       hash,
+		partitions: make([]*Queue[E], partitions),
+	}
+	for i := range q.partitions {
+		q.partitions[i] = New[E](capacity, opts...)
+	}
+	return q
+}
+
+// Put 将数据放入键 key 所在的分区。返回该分区剩余可填充数据个数。若该分区已满返回错误 ErrQueueIsFull。
+func (q *PartitionedQueue[K, E]) Put(key K, value E) (uint32, error) {
+	return q.partitions[q.PartitionOf(key)].Put(value)
+}
+
+// PartitionOf 返回键 key 所在分区的下标。
+func (q *PartitionedQueue[K, E]) PartitionOf(key K) int {
+	return int(q.hash(key) % uint64(len(q.partitions)))
+}
+
+// Partition 返回第 i 个分区，供该分区的消费者取出数据。
+func (q *PartitionedQueue[K, E]) Partition(i int) *Queue[E] {
+	return q.partitions[i]
+}
+
+// Partitions 返回分区个数。
+func (q *PartitionedQueue[K, E]) Partitions() int {
+	return len(q.partitions)
+}
+
+// Cap 返回所有分区的总长度。
+func (q *PartitionedQueue[K, E]) Cap() uint32 {
+	var n uint32
+	for _, p := range q.partitions {
+		n += p.Cap()
+	}
+	return n
+}
+
+// Len 返回所有分区的数据个数之和。
+func (q *PartitionedQueue[K, E]) Len() uint32 {
+	var n uint32
+	for _, p := range q.partitions {
+		n += p.Len()
+	}
+	return n
+}
+
+// Lens 返回每个分区的数据个数，用于观察分区是否倾斜。
+func (q *PartitionedQueue[K, E]) Lens() []uint32 {
+	lens := make([]uint32, len(q.partitions))
+	for i, p := range q.partitions {
+		lens[i] = p.Len()
+	}
+	return lens
+}
+
+// Stats 返回所有分区的汇总状态。Len、Free、Cap、MaxLen 及各项统计数据为各分区之和，其中 MaxLen 为各分区历史最高值之和；
+// Closed、Paused 仅在所有分区均已关闭、均已暂停时为真；Head、Tail 对分区组无意义，恒为零。
+//
+// 各分区依次读取，并发操作时结果并非同一时刻的状态。
+func (q *PartitionedQueue[K, E]) Stats() QueueStats {
+	stats := QueueStats{Closed: true, Paused: true}
+	for _, p := range q.partitions {
+		s := p.Stats()
+		stats.Len += s.Len
+		stats.Free += s.Free
+		stats.Cap += s.Cap
+		stats.MaxLen += s.MaxLen
+		stats.Closed = stats.Closed && s.Closed
+		stats.Paused = stats.Paused && s.Paused
+		stats.Puts += s.Puts
+		stats.Gets += s.Gets
+		stats.CASRetries += s.CASRetries
+		stats.FullFailures += s.FullFailures
+		stats.EmptyFailures += s.EmptyFailures
+	}
+	return stats
+}
+
+// Close 关闭所有分区。
+func (q *PartitionedQueue[K, E]) Close() {
+	for _, p := range q.partitions {
+		p.Close()
+	}
+}
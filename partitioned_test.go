@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"hash/fnv"
+	"sync"
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestPartitionedQueue(t *testing.T) {
+	type order struct {
+		user string
+		seq  int
+	}
+	hash := func(key string) uint64 {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		return h.Sum64()
+	}
+	users := []string{"alice", "bob", "carol", "dave", "erin"}
+	q := queue.NewPartitioned[string, order](3, 64, hash)
+
+	wg := sync.WaitGroup{}
+	for _, u := range users {
+		wg.Add(1)
+		go func(u string) {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				q.Partition(q.PartitionOf(u)).MustPut(order{u, i})
+			}
+		}(u)
+	}
+	wg.Wait()
+	if q.Len() != 50 || q.Cap() != 3*64 {
+		t.Fatal("Len/Cap mismatch")
+	}
+	var total uint32
+	for _, n := range q.Lens() {
+		total += n
+	}
+	if total != 50 {
+		t.Fatal("Lens sum != 50")
+	}
+
+	for i := 0; i < q.Partitions(); i++ {
+		last := map[string]int{}
+		q.Partition(i).DrainEach(func(o order) {
+			if q.PartitionOf(o.user) != i {
+				t.Fatal("key in wrong partition")
+			}
+			if prev, ok := last[o.user]; ok && o.seq != prev+1 {
+				t.Fatal("per-key order violated")
+			}
+			last[o.user] = o.seq
+		})
+	}
+
+	if _, err := q.Put("alice", order{"alice", 10}); err != nil {
+		t.Fatal(err)
+	}
+	q.Close()
+	if _, err := q.Put("alice", order{"alice", 11}); err != queue.ErrQueueIsClosed {
+		t.Fatal("err != ErrQueueIsClosed")
+	}
+}
+
+func TestPartitionedStats(t *testing.T) {
+	q := queue.NewPartitioned[int, int](2, 4, func(k int) uint64 { return uint64(k) }, queue.WithStats())
+	for i := 0; i < 5; i++ {
+		q.Put(0, i)
+	}
+	q.Put(1, 1)
+	q.Partition(1).Get()
+	stats := q.Stats()
+	if stats.Len != 4 || stats.Free != 4 || stats.Cap != 8 || stats.MaxLen != 5 {
+		t.Fatalf("%+v", stats)
+	}
+	if stats.Puts != 5 || stats.Gets != 1 || stats.FullFailures != 1 || stats.Closed || stats.Paused {
+		t.Fatalf("%+v", stats)
+	}
+	q.Partition(0).Close()
+	if q.Stats().Closed {
+		t.Fatal("closed before all partitions closed")
+	}
+	q.Close()
+	if !q.Stats().Closed {
+		t.Fatal("not closed")
+	}
+}
@@ -0,0 +1,225 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import "sync/atomic"
+
+// Peek 返回队列头部数据但不取出。无数据时返回错误 ErrQueueIsEmpty，或 WithErrors 设置的错误；队列已关闭且无数据时返回 ErrQueueIsClosed。
+//
+// 并发取出时，返回的数据可能随即被其他协程取出。
+func (q *Queue[E]) Peek() (E, error) {
+	q.enter()
+	defer q.leave()
+	for {
+		head := atomic.LoadUint32(&q.head)
+		if head == atomic.LoadUint32(&q.tail) {
+			var empty E
+			if q.isClosed() {
+				return empty, ErrQueueIsClosed
+			}
+			return empty, q.errEmpty
+		}
+		if val, ok := q.peek(head + 1); ok {
+			return val, nil
+		}
+	}
+}
+
+// GetIf 当 pred 接受队列头部数据时将其取出。返回数据，是否取出。无数据时返回的错误同 Get。
+//
+// pred 执行期间头部位置被锁定，其他协程对该位置的取出将等待，pred 应尽快返回且不可操作本队列。
+func (q *Queue[E]) GetIf(pred func(E) bool) (E, bool, error) {
+	var empty E
+	q.enter()
+	defer q.leave()
+	for {
+		head := atomic.LoadUint32(&q.head)
+		if head == atomic.LoadUint32(&q.tail) {
+			if q.isClosed() {
+				return empty, false, ErrQueueIsClosed
+			}
+			if q.counters != nil {
+				atomic.AddUint64(&q.counters.emptyFailures, 1)
+			}
+			return empty, false, q.errEmpty
+		}
+		position := head + 1
+		elem, ok := q.lockSlot(position)
+		if !ok {
+			continue
+		}
+		if !pred(elem.value) {
+			atomic.StoreUint32(&elem.getSeq, position)
+			return empty, false, nil
+		}
+		if !atomic.CompareAndSwapUint32(&q.head, head, position) {
+			atomic.StoreUint32(&elem.getSeq, position)
+			continue
+		}
+		if q.counters != nil {
+			atomic.AddUint64(&q.counters.gets, 1)
+		}
+		if q.callers != nil {
+			q.callers.trackConsumer()
+		}
+		return q.take(position, elem), true, nil
+	}
+}
+
+// PeekLast 返回队列尾部最新填充的数据但不取出。无数据时返回的错误同 Peek。
+//
+// 尾部位置已被认领但数据尚未写入时，将等待写入完成。
+func (q *Queue[E]) PeekLast() (E, error) {
+	q.enter()
+	defer q.leave()
+	for {
+		tail := atomic.LoadUint32(&q.tail)
+		if tail == atomic.LoadUint32(&q.head) {
+			var empty E
+			if q.isClosed() {
+				return empty, ErrQueueIsClosed
+			}
+			return empty, q.errEmpty
+		}
+		if val, ok := q.peek(tail); ok {
+			return val, nil
+		}
+	}
+}
+
+// At 返回从头部起第 i 个数据但不取出，头部为第0个。i 超出数据个数时返回 false。
+//
+// 尽力而为的读取，并发取出时该数据可能已被取出，此时同样返回 false。
+func (q *Queue[E]) At(i uint32) (E, bool) {
+	q.enter()
+	defer q.leave()
+	head := atomic.LoadUint32(&q.head)
+	if i >= q.usedSize(atomic.LoadUint32(&q.tail), head) {
+		var empty E
+		return empty, false
+	}
+	return q.peek(head + 1 + i)
+}
+
+// PeekMany 从头部起按先进先出顺序返回至多 n 个数据但不取出。
+//
+// 逐个读取数据，并发取出时，遇到已被取出的数据即停止，返回的数据个数可能少于 n。
+func (q *Queue[E]) PeekMany(n uint32) []E {
+	q.enter()
+	defer q.leave()
+	head := atomic.LoadUint32(&q.head)
+	used := q.usedSize(atomic.LoadUint32(&q.tail), head)
+	if n > used {
+		n = used
+	}
+	res := make([]E, 0, n)
+	for i, end := head+1, head+1+n; i != end; i++ {
+		val, ok := q.peek(i)
+		if !ok {
+			break
+		}
+		res = append(res, val)
+	}
+	return res
+}
+
+// Snapshot 按先进先出顺序复制队列当前所有数据但不取出，适合调试或展示待处理的数据。
+//
+// 尽力而为的快照，并发取出时返回的数据可能少于调用时刻的数据个数，参见 PeekMany。
+func (q *Queue[E]) Snapshot() []E {
+	return q.PeekMany(q.Cap())
+}
+
+// Clone 创建容量和配置项相同的新队列，并按先进先出顺序复制当前所有数据，原队列不受影响。
+//
+// 数据复制方式同 Snapshot。新队列未关闭、未暂停，统计数据从零开始。
+func (q *Queue[E]) Clone() *Queue[E] {
+	clone := New[E](q.Cap(), func(o *options) { *o = q.opts })
+	atomic.StoreInt64(&clone.maxBlock, atomic.LoadInt64(&q.maxBlock))
+	clone.PutEnough(q.Snapshot()...)
+	return clone
+}
+
+// Contains 判断队列当前数据中是否有等于 v 的数据，不取出数据。
+//
+// 逐个读取数据，并发取出时跳过已被取出的数据，结果仅反映扫描期间的状态。
+func Contains[E comparable](q *Queue[E], v E) bool {
+	found := false
+	q.scan(func(val E) bool {
+		found = val == v
+		return !found
+	})
+	return found
+}
+
+// Count 返回队列当前数据中等于 v 的数据个数，不取出数据。扫描方式同 Contains。
+func Count[E comparable](q *Queue[E], v E) uint32 {
+	var n uint32
+	q.scan(func(val E) bool {
+		if val == v {
+			n++
+		}
+		return true
+	})
+	return n
+}
+
+// 按先进先出顺序读取当前所有数据并调用 fn，fn 返回 false 时停止。已被取出的数据将跳过。
+func (q *Queue[E]) scan(fn func(E) bool) {
+	q.enter()
+	defer q.leave()
+	head := atomic.LoadUint32(&q.head)
+	tail := atomic.LoadUint32(&q.tail)
+	if q.usedSize(tail, head) > q.capacity {
+		return
+	}
+	for i := head + 1; i != tail+1; i++ {
+		if val, ok := q.peek(i); ok && !fn(val) {
+			return
+		}
+	}
+}
+
+// 读取位置上的数据但不取出。该位置数据已被取出时返回 false。
+func (q *Queue[E]) peek(position uint32) (E, bool) {
+	elem, ok := q.lockSlot(position)
+	if !ok {
+		var empty E
+		return empty, false
+	}
+	val := elem.value
+	atomic.StoreUint32(&elem.getSeq, position)
+	return val, true
+}
+
+// 锁定位置上已写入的数据，返回对应元素。该位置数据已被取出时返回 false。
+// 解锁时将 getSeq 恢复为 position，或以 take 取出数据。
+//
+// 同取出数据一样，将元素的 getSeq 改为 position-1 以锁定该位置，该值不会是该元素的有效序号，
+// 取出方和下一轮填充方将等待至解锁。该位置的填充尚未完成，或上一轮数据尚未取出完成时等待。
+func (q *Queue[E]) lockSlot(position uint32) (*element[E], bool) {
+	elem := q.slot(position)
+	for i := 0; ; i++ {
+		switch atomic.LoadUint32(&elem.getSeq) {
+		case position:
+			if atomic.LoadUint32(&elem.putSeq) == position+q.capacity &&
+				atomic.CompareAndSwapUint32(&elem.getSeq, position, position-1) {
+				return elem, true
+			}
+		case position - 1, position - q.capacity:
+		default:
+			return nil, false
+		}
+		q.strategy.Wait(i)
+	}
+}
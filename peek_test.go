@@ -0,0 +1,239 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestPeek(t *testing.T) {
+	q := queue.New[int](4)
+	if _, err := q.Peek(); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+	if len(q.PeekMany(2)) != 0 {
+		t.Fatal("peek from empty queue")
+	}
+	q.PutEnough(1, 2, 3)
+	if val, err := q.Peek(); err != nil || val != 1 {
+		t.Fatal("val != 1")
+	}
+	if q.Len() != 3 {
+		t.Fatal("len != 3")
+	}
+	values := q.PeekMany(5)
+	if len(values) != 3 || values[0] != 1 || values[2] != 3 {
+		t.Fatal("values != [1 2 3]")
+	}
+	if values = q.PeekMany(2); len(values) != 2 || values[1] != 2 {
+		t.Fatal("values != [1 2]")
+	}
+	if val, err := q.PeekLast(); err != nil || val != 3 {
+		t.Fatal("val != 3")
+	}
+	if values = q.Snapshot(); len(values) != 3 || values[0] != 1 || values[2] != 3 || q.Len() != 3 {
+		t.Fatal("snapshot != [1 2 3]")
+	}
+	for i := 1; i <= 3; i++ {
+		if val, _, _ := q.Get(); val != i {
+			t.Fatal("val mismatch")
+		}
+	}
+	if _, err := q.PeekLast(); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+	q.Close()
+	if _, err := q.Peek(); err != queue.ErrQueueIsClosed {
+		t.Fatal("err != ErrQueueIsClosed")
+	}
+}
+
+func TestPeekConcurrent(t *testing.T) {
+	q := queue.New[int](8)
+	const count = 20000
+	done := make(chan struct{})
+	wg := sync.WaitGroup{}
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= count; i++ {
+			q.MustPut(i)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for i := 1; i <= count; i++ {
+			if val, _ := q.MustGet(); val != i {
+				t.Errorf("val %d != %d", val, i)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		last := 0
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				runtime.Gosched()
+			}
+			values := q.PeekMany(8)
+			for j := 1; j < len(values); j++ {
+				if values[j] != values[j-1]+1 {
+					t.Errorf("values not continuous: %v", values)
+					return
+				}
+			}
+			if val, err := q.PeekLast(); err == nil && val < last {
+				t.Errorf("peek last %d < %d", val, last)
+				return
+			}
+			if val, err := q.Peek(); err == nil {
+				if val < last {
+					t.Errorf("peek %d < %d", val, last)
+					return
+				}
+				last = val
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+func TestClone(t *testing.T) {
+	q := queue.New[int](3, queue.WithExactCapacity())
+	q.PutEnough(1, 2)
+	clone := q.Clone()
+	if clone.Cap() != 3 || clone.Len() != 2 || q.Len() != 2 {
+		t.Fatal("clone mismatch")
+	}
+	clone.Put(3)
+	if _, err := clone.Put(4); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	for i := 1; i <= 3; i++ {
+		if val, _, _ := clone.Get(); val != i {
+			t.Fatal("val mismatch")
+		}
+	}
+	if val, _ := q.Peek(); val != 1 || q.Len() != 2 {
+		t.Fatal("original changed")
+	}
+}
+
+func TestGetIf(t *testing.T) {
+	q := queue.New[int](4)
+	if _, _, err := q.GetIf(func(int) bool { return true }); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+	q.PutEnough(1, 2)
+	if _, ok, err := q.GetIf(func(v int) bool { return v > 1 }); ok || err != nil || q.Len() != 2 {
+		t.Fatal("head removed")
+	}
+	if val, ok, _ := q.GetIf(func(v int) bool { return v == 1 }); !ok || val != 1 || q.Len() != 1 {
+		t.Fatal("head not removed")
+	}
+	if val, _, _ := q.Get(); val != 2 {
+		t.Fatal("val != 2")
+	}
+
+	const count = 10000
+	q = queue.New[int](8)
+	wg := sync.WaitGroup{}
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= count; i++ {
+			q.MustPut(i)
+		}
+	}()
+	var mu sync.Mutex
+	seen := make(map[int]bool, count)
+	consume := func(get func() (int, bool)) {
+		defer wg.Done()
+		for {
+			mu.Lock()
+			if len(seen) == count {
+				mu.Unlock()
+				return
+			}
+			mu.Unlock()
+			val, ok := get()
+			if !ok {
+				runtime.Gosched()
+				continue
+			}
+			mu.Lock()
+			if seen[val] {
+				t.Errorf("val %d consumed twice", val)
+			}
+			seen[val] = true
+			mu.Unlock()
+		}
+	}
+	go consume(func() (int, bool) {
+		val, ok, _ := q.GetIf(func(int) bool { return true })
+		return val, ok
+	})
+	go consume(func() (int, bool) {
+		val, _, err := q.Get()
+		return val, err == nil
+	})
+	wg.Wait()
+}
+
+func TestContainsCount(t *testing.T) {
+	q := queue.New[string](8)
+	if queue.Contains(q, "a") || queue.Count(q, "a") != 0 {
+		t.Fatal("found in empty queue")
+	}
+	q.PutEnough("a", "b", "a", "c")
+	if !queue.Contains(q, "c") || queue.Contains(q, "d") {
+		t.Fatal("contains mismatch")
+	}
+	if queue.Count(q, "a") != 2 || q.Len() != 4 {
+		t.Fatal("count != 2")
+	}
+	q.Get()
+	if queue.Count(q, "a") != 1 {
+		t.Fatal("count != 1")
+	}
+}
+
+func TestAt(t *testing.T) {
+	q := queue.New[int](4)
+	if _, ok := q.At(0); ok {
+		t.Fatal("at from empty queue")
+	}
+	q.Get()
+	q.PutEnough(1, 2, 3, 4)
+	for i := uint32(0); i < 4; i++ {
+		if val, ok := q.At(i); !ok || val != int(i)+1 {
+			t.Fatal("val mismatch")
+		}
+	}
+	if _, ok := q.At(4); ok {
+		t.Fatal("at out of range")
+	}
+	q.Get()
+	if val, _ := q.At(0); val != 2 {
+		t.Fatal("val != 2")
+	}
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+// Pool 有界对象池。与 sync.Pool 不同，池中对象不会被 GC 回收，适合复用缓冲区等对象。使用 NewPool 创建变量。
+type Pool[E any] struct {
+	q       *Queue[E]
+	newFn   func() E
+	resetFn func(E) E
+}
+
+// NewPool 创建对象池。capacity 池中最多保留对象个数，调整规则同 New。
+// newFn 池中无对象时创建新对象。resetFn 对象放回池前重置，返回重置后的对象，可为 nil。
+func NewPool[E any](capacity uint32, newFn func() E, resetFn func(E) E) *Pool[E] {
+	if newFn == nil {
+		panic("newFn is nil")
+	}
+	return &Pool[E]{q: New[E](capacity), newFn: newFn, resetFn: resetFn}
+}
+
+// Get 从池中取出对象，池中无对象时调用 newFn 创建。
+func (p *Pool[E]) Get() E {
+	if value, _, err := p.q.Get(); err == nil {
+		return value
+	}
+	return p.newFn()
+}
+
+// Put 重置对象并放回池中。池已满时丢弃对象，返回 false。
+func (p *Pool[E]) Put(value E) bool {
+	if p.resetFn != nil {
+		value = p.resetFn(value)
+	}
+	_, err := p.q.tryPut(value)
+	return err == nil
+}
+
+// Cap 返回池中最多保留对象个数。
+func (p *Pool[E]) Cap() uint32 {
+	return p.q.Cap()
+}
+
+// Len 返回池中空闲对象个数。
+func (p *Pool[E]) Len() uint32 {
+	return p.q.Len()
+}
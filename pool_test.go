@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestPool(t *testing.T) {
+	var created int32
+	p := queue.NewPool[[]byte](2, func() []byte {
+		atomic.AddInt32(&created, 1)
+		return make([]byte, 0, 16)
+	}, func(b []byte) []byte {
+		return b[:0]
+	})
+
+	b := p.Get()
+	if created != 1 || len(b) != 0 || cap(b) != 16 {
+		t.Fatal("created != 1")
+	}
+	b = append(b, 1, 2, 3)
+	if !p.Put(b) {
+		t.Fatal("put failed")
+	}
+	if p.Len() != 1 {
+		t.Fatal("len != 1")
+	}
+	b = p.Get()
+	if created != 1 || len(b) != 0 {
+		t.Fatal("object not reused or not reset")
+	}
+
+	for i := 0; i < 2; i++ {
+		if !p.Put(p.Get()) {
+			t.Fatal("put failed")
+		}
+	}
+	p.Put(make([]byte, 0, 16))
+	if p.Put(make([]byte, 0, 16)) {
+		t.Fatal("put into full pool")
+	}
+	if p.Len() != p.Cap() {
+		t.Fatal("len != cap")
+	}
+}
+
+func TestPoolConcurrent(t *testing.T) {
+	var created int32
+	p := queue.NewPool[*int](8, func() *int {
+		atomic.AddInt32(&created, 1)
+		return new(int)
+	}, nil)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				v := p.Get()
+				*v++
+				p.Put(v)
+			}
+		}()
+	}
+	wg.Wait()
+	if p.Len() > p.Cap() || atomic.LoadInt32(&created) < int32(p.Len()) {
+		t.Fatal("len out of range")
+	}
+}
@@ -0,0 +1,191 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type (
+	// PriorityQueue 优先级队列。Get 返回优先级最高的数据，优先级相同时先进先出。使用 NewPriorityQueue 创建变量。
+	//
+	// 内部为互斥锁保护的二叉堆，填充和取出的时间复杂度为 O(log n)。
+	PriorityQueue[E any] struct {
+		mu       sync.Mutex
+		capacity uint32
+		heap     priorityHeap[E]
+	}
+
+	// 二叉堆，优先级高者在堆顶，优先级相同时先进先出。
+	priorityHeap[E any] struct {
+		seq   uint64
+		items []priorityItem[E]
+	}
+
+	priorityItem[E any] struct {
+		priority int64
+		seq      uint64 // 填充顺序，用于优先级相同时保持先进先出。
+		value    E
+	}
+)
+
+// NewPriorityQueue 创建优先级队列。capacity 队列长度，调整规则同 New。
+func NewPriorityQueue[E any](capacity uint32) *PriorityQueue[E] {
+	capacity = roundCapacity(capacity)
+	return &PriorityQueue[E]{
+		capacity: capacity,
+		heap:     priorityHeap[E]{items: make([]priorityItem[E], 0, capacity)},
+	}
+}
+
+// Put 以优先级 priority 填充数据，值越大越优先。返回剩余可填充数据个数。若队列已满返回错误 ErrQueueIsFull。
+func (q *PriorityQueue[E]) Put(priority int, value E) (uint32, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if uint32(q.heap.len()) == q.capacity {
+		return 0, ErrQueueIsFull
+	}
+	q.heap.push(int64(priority), value)
+	return q.capacity - uint32(q.heap.len()), nil
+}
+
+// PutDeadline 以截止时间 deadline 填充数据，截止时间越早越优先，实现最早截止时间优先调度。返回剩余可填充数据个数。
+// 若队列已满返回错误 ErrQueueIsFull。deadline 为零值时视为最紧急。
+//
+// 截止时间以 -UnixNano 作为优先级，与 Put 混用时按该优先级比较，通常应只使用其中一种。
+func (q *PriorityQueue[E]) PutDeadline(value E, deadline time.Time) (uint32, error) {
+	priority := int64(math.MaxInt64)
+	if !deadline.IsZero() {
+		priority = -deadline.UnixNano()
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if uint32(q.heap.len()) == q.capacity {
+		return 0, ErrQueueIsFull
+	}
+	q.heap.push(priority, value)
+	return q.capacity - uint32(q.heap.len()), nil
+}
+
+// PutEnough 以同一优先级填充多个数据。返回实际填充数据个数，剩余可填充数据个数。
+func (q *PriorityQueue[E]) PutEnough(priority int, values ...E) (uint32, uint32) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	left := q.capacity - uint32(q.heap.len())
+	size := uint32(len(values))
+	if size > left {
+		size = left
+	}
+	for _, v := range values[:size] {
+		q.heap.push(int64(priority), v)
+	}
+	return size, left - size
+}
+
+// Get 取出优先级最高的数据。返回队列数据，队列剩余可取个数。当无数据可取时返回错误 ErrQueueIsEmpty。
+func (q *PriorityQueue[E]) Get() (E, uint32, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.heap.len() == 0 {
+		var empty E
+		return empty, 0, ErrQueueIsEmpty
+	}
+	return q.heap.pop(), uint32(q.heap.len()), nil
+}
+
+// GetEnough 按优先级从高到低取出多个数据。返回队列数据，实际取出数据个数，剩余可取数据个数。
+func (q *PriorityQueue[E]) GetEnough(size uint32) ([]E, uint32, uint32) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if used := uint32(q.heap.len()); size > used {
+		size = used
+	}
+	res := make([]E, 0, size)
+	for i := uint32(0); i < size; i++ {
+		res = append(res, q.heap.pop())
+	}
+	return res, size, uint32(q.heap.len())
+}
+
+// Cap 返回队列长度。
+func (q *PriorityQueue[E]) Cap() uint32 {
+	return q.capacity
+}
+
+// Len 返回队列数据个数。
+func (q *PriorityQueue[E]) Len() uint32 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return uint32(q.heap.len())
+}
+
+// IsEmpty 判断队列是否有数据。
+func (q *PriorityQueue[E]) IsEmpty() bool {
+	return q.Len() == 0
+}
+
+// IsFull 判断队列是否已满。
+func (q *PriorityQueue[E]) IsFull() bool {
+	return q.Len() == q.capacity
+}
+
+func (h *priorityHeap[E]) len() int {
+	return len(h.items)
+}
+
+func (h *priorityHeap[E]) push(priority int64, value E) {
+	h.seq++
+	h.items = append(h.items, priorityItem[E]{priority: priority, seq: h.seq, value: value})
+	for i := len(h.items) - 1; i > 0; {
+		parent := (i - 1) / 2
+		if !h.less(i, parent) {
+			break
+		}
+		h.items[i], h.items[parent] = h.items[parent], h.items[i]
+		i = parent
+	}
+}
+
+func (h *priorityHeap[E]) pop() E {
+	n := len(h.items) - 1
+	val := h.items[0].value
+	h.items[0] = h.items[n]
+	h.items[n] = priorityItem[E]{}
+	h.items = h.items[:n]
+	for i := 0; ; {
+		top, left, right := i, 2*i+1, 2*i+2
+		if left < n && h.less(left, top) {
+			top = left
+		}
+		if right < n && h.less(right, top) {
+			top = right
+		}
+		if top == i {
+			break
+		}
+		h.items[i], h.items[top] = h.items[top], h.items[i]
+		i = top
+	}
+	return val
+}
+
+// 下标 i 的数据是否应先于下标 j 的数据取出。
+func (h *priorityHeap[E]) less(i, j int) bool {
+	a, b := &h.items[i], &h.items[j]
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	return a.seq < b.seq
+}
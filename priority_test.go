@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestPriorityQueue(t *testing.T) {
+	q := queue.NewPriorityQueue[string](4)
+	if _, _, err := q.Get(); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+	q.Put(1, "low")
+	q.Put(5, "high-1")
+	q.Put(3, "mid")
+	q.Put(5, "high-2")
+	if _, err := q.Put(9, "full"); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	vals, size, used := q.GetEnough(4)
+	if size != 4 || used != 0 {
+		t.Fatal("GetEnough mismatch")
+	}
+	for i, want := range []string{"high-1", "high-2", "mid", "low"} {
+		if vals[i] != want {
+			t.Fatalf("vals[%d] %s != %s", i, vals[i], want)
+		}
+	}
+	if n, left := q.PutEnough(2, "a", "b", "c", "d", "e"); n != 4 || left != 0 {
+		t.Fatal("PutEnough mismatch")
+	}
+}
+
+func TestPriorityQueueConcurrent(t *testing.T) {
+	const (
+		producers = 8
+		count     = 500
+	)
+	q := queue.NewPriorityQueue[int](producers * count)
+	wg := sync.WaitGroup{}
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				pri := rand.Intn(100)
+				if _, err := q.Put(pri, pri); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	last := 100
+	for !q.IsEmpty() {
+		val, _, _ := q.Get()
+		if val > last {
+			t.Fatal("priority order violated")
+		}
+		last = val
+	}
+}
+
+func TestPutDeadline(t *testing.T) {
+	q := queue.NewPriorityQueue[string](4)
+	now := time.Now()
+	q.PutDeadline("c", now.Add(3*time.Second))
+	q.PutDeadline("a", now.Add(time.Second))
+	q.PutDeadline("d", now.Add(4*time.Second))
+	if left, err := q.PutDeadline("b", now.Add(2*time.Second)); err != nil || left != 0 {
+		t.Fatal("left != 0")
+	}
+	if _, err := q.PutDeadline("e", now); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	for _, expected := range []string{"a", "b", "c", "d"} {
+		if val, _, _ := q.Get(); val != expected {
+			t.Fatalf("val %s != %s", val, expected)
+		}
+	}
+
+	q.PutDeadline("later", now)
+	q.PutDeadline("zero", time.Time{})
+	if val, _, _ := q.Get(); val != "zero" {
+		t.Fatal("zero deadline not first")
+	}
+}
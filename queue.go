@@ -13,10 +13,13 @@
 package safe_queue
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"math/bits"
 	"runtime"
+	"sync"
 	"sync/atomic"
+	"time"
 	"unsafe"
 
 	"golang.org/x/sys/cpu"
@@ -24,34 +27,206 @@ import (
 
 const cacheLinePadSize = unsafe.Sizeof(cpu.CacheLinePad{})
 
+// Drain 每次认领的数据个数上限。
+const drainBatchSize = 64
+
 var (
 	// ErrQueueIsFull 表明队列已满。
-	ErrQueueIsFull = errors.New("队列已满")
+	ErrQueueIsFull = newError("队列已满", "queue is full")
 	// ErrQueueIsEmpty 表明队列为空。
-	ErrQueueIsEmpty = errors.New("队列为空")
+	ErrQueueIsEmpty = newError("队列为空", "queue is empty")
+	// ErrQueueIsClosed 表明队列已关闭。
+	ErrQueueIsClosed = newError("队列已关闭", "queue is closed")
+	// ErrQueuePaused 表明队列已暂停填充。
+	ErrQueuePaused = newError("队列已暂停填充", "queue is paused")
+	// ErrNotEnough 表明队列数据个数不足。
+	ErrNotEnough = newError("队列数据不足", "not enough data in queue")
+	// ErrBlockTimeout 表明阻塞等待超过了 SetMaxBlockDuration 设置的时长。
+	ErrBlockTimeout = newError("阻塞等待超时", "blocking wait timed out")
+	// ErrNotResizable 表明队列未使用 WithResizable 创建，不可调整容量。
+	ErrNotResizable = newError("队列不可调整容量", "queue is not resizable")
+	// ErrOptionType 表明泛型配置项的元素类型与队列元素类型不一致。
+	ErrOptionType = newError("配置项的元素类型与队列不一致", "option element type does not match queue")
 )
 
 type (
 	// Queue 队列结构体。使用 New 创建变量。
 	Queue[E any] struct {
 		capacity, mask uint32
-		_              [cacheLinePadSize - 8]byte
+		limit          uint32
+		_              [cacheLinePadSize - 12]byte
 		head           uint32
 		_              [cacheLinePadSize - 4]byte
 		tail           uint32
 		_              [cacheLinePadSize - 4]byte
 		elements       []element[E]
 		_              [cacheLinePadSize - unsafe.Sizeof([]element[E]{})]byte
+		maxBlock       int64
+		fillEWMA       uint64
+		ewmaAlpha      float64
+		maxLen         uint32
+		closed         uint32
+		paused         uint32
+		backend        uint32
+		weight         uint32
+		maxWeight      uint32
+		weightOf       func(E) uint32
+		fullPolicy     FullPolicy
+		onDrop         func(E)
+		codec          ElementCodec[E]
+		strategy       WaitStrategy
+		errFull        error
+		errEmpty       error
+		counters       *counters
+		consumeLog     *consumeLog
+		callers        *callerTracker
+		attribution    *sync.Map
+		gate           *sync.RWMutex
+		segments       [][]element[E]
+		segmentSize    uint32
+		segmentShift   uint32
+		strideShift    uint32
+		sink           MetricsSink
+		growMax        uint32
+		shrinkMin      uint32
+		shrinkAfter    int64
+		lowSince       int64
+		seqOffset      uint32
+		opts           options
+		closeOnce      sync.Once
+		done           chan struct{}
+		drainOnce      sync.Once
+		drained        chan struct{}
+		notEmpty       notifier
+		notFull        notifier
 	}
 	element[E any] struct {
 		getSeq, putSeq uint32
 		value          E
-		_              [cacheLinePadSize - 8 - 16]byte
 	}
 )
 
 // New 创建队列。capacity 队列长度。值将调整为以2为底的幂数，最小值为2，最大值为2^31。最终队列容量将大于capacity。
-func New[E any](capacity uint32) *Queue[E] {
+// 需要严格按 capacity 限制数据个数时使用 WithExactCapacity。
+// opts 队列配置项。需要无缓冲的同步交接时使用 NewRendezvous。
+//
+// WithWeights、WithFullPolicy、WithElementCodec 的元素类型与 E 不一致时 panic，需要以错误处理时使用 NewE。
+func New[E any](capacity uint32, opts ...Option) *Queue[E] {
+	q, err := NewE[E](capacity, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+// NewE 同 New，但 WithWeights、WithFullPolicy、WithElementCodec 的元素类型与 E 不一致时返回错误 ErrOptionType，而不是 panic。
+func NewE[E any](capacity uint32, opts ...Option) (*Queue[E], error) {
+	requested := capacity
+	capacity = roundCapacity(capacity)
+
+	instance := &Queue[E]{
+		capacity: capacity,
+		limit:    capacity,
+		done:     make(chan struct{}),
+		drained:  make(chan struct{}),
+		errFull:  ErrQueueIsFull,
+		errEmpty: ErrQueueIsEmpty,
+		strategy: defaultBackoff,
+		backend:  uint32(ParkBackend),
+	}
+	for _, opt := range opts {
+		opt(&instance.opts)
+	}
+	if instance.opts.exactCapacity && requested > 0 {
+		instance.limit = requested
+	}
+	if instance.opts.backend != nil {
+		instance.backend = uint32(*instance.opts.backend)
+	}
+	if instance.opts.strategy != nil {
+		instance.strategy = instance.opts.strategy
+		if _, ok := instance.strategy.(BlockingWait); ok {
+			instance.backend = uint32(ParkBackend)
+		}
+	}
+	if instance.opts.detailedErrors {
+		instance.errFull = &FullError{Name: instance.opts.name, Capacity: instance.limit, Requested: 1}
+		instance.errEmpty = &EmptyError{Name: instance.opts.name}
+	}
+	if instance.opts.errFull != nil {
+		instance.errFull = instance.opts.errFull
+	}
+	if instance.opts.errEmpty != nil {
+		instance.errEmpty = instance.opts.errEmpty
+	}
+	var ok bool
+	if instance.opts.weightOf != nil {
+		if instance.weightOf, ok = instance.opts.weightOf.(func(E) uint32); !ok {
+			return nil, fmt.Errorf("%w: WithWeights", ErrOptionType)
+		}
+		instance.maxWeight = instance.opts.maxWeight
+	}
+	instance.fullPolicy = instance.opts.fullPolicy
+	if instance.opts.onDrop != nil {
+		if instance.onDrop, ok = instance.opts.onDrop.(func(E)); !ok {
+			return nil, fmt.Errorf("%w: WithFullPolicy", ErrOptionType)
+		}
+	}
+	if instance.opts.codec != nil {
+		if instance.codec, ok = instance.opts.codec.(ElementCodec[E]); !ok {
+			return nil, fmt.Errorf("%w: WithElementCodec", ErrOptionType)
+		}
+	}
+	if alpha := instance.opts.ewmaAlpha; alpha > 0 {
+		if alpha > 1 {
+			alpha = 1
+		}
+		instance.ewmaAlpha = alpha
+	}
+	if instance.opts.stats {
+		instance.counters = &counters{}
+	}
+	if o := instance.opts; o.singleProducer || o.singleConsumer {
+		instance.callers = &callerTracker{
+			singleProducer: o.singleProducer,
+			singleConsumer: o.singleConsumer,
+			onViolation:    o.onViolation,
+		}
+	}
+	if instance.opts.consumeLog {
+		instance.consumeLog = &consumeLog{}
+	}
+	if instance.opts.attribution {
+		instance.attribution = &sync.Map{}
+	}
+	if instance.opts.resizable || instance.opts.growMax > 0 {
+		instance.gate = &sync.RWMutex{}
+	}
+	if instance.opts.growMax > 0 {
+		instance.growMax = instance.limitOf(instance.opts.growMax)
+		instance.shrinkMin = instance.limit
+		instance.shrinkAfter = int64(instance.opts.shrinkAfter)
+	}
+	if size := instance.opts.segmentSize; size > 0 {
+		instance.segmentSize = roundCapacity(size)
+	}
+	if instance.opts.padding == PadSlots {
+		for unsafe.Sizeof(element[E]{})<<instance.strideShift < cacheLinePadSize {
+			instance.strideShift++
+		}
+	}
+	instance.sink = instance.opts.sink
+	instance.allocate(capacity)
+
+	if instance.opts.observer != nil {
+		go instance.observe()
+	}
+
+	return instance, nil
+}
+
+// 将容量调整为以2为底的幂数，最小值为2。
+func roundCapacity(capacity uint32) uint32 {
 	capacity--
 	capacity |= capacity >> 1
 	capacity |= capacity >> 2
@@ -63,59 +238,240 @@ func New[E any](capacity uint32) *Queue[E] {
 	if capacity < 2 {
 		capacity = 2
 	}
+	return capacity
+}
 
-	instance := &Queue[E]{
-		capacity: capacity,
-		elements: make([]element[E], capacity),
-		mask:     capacity - 1,
+// Put 向队列尾部填充数据。返回剩余可填充数据个数。若队列已满返回错误 ErrQueueIsFull，或 WithErrors 设置的错误。
+// 队列已关闭时返回 ErrQueueIsClosed，队列已暂停时返回 ErrQueuePaused。
+//
+// 使用 WithFullPolicy 创建的队列，队列已满时按设置的策略处理。
+func (q *Queue[E]) Put(value E) (uint32, error) {
+	start := q.startReport()
+	left, err := q.tryPut(value)
+	if err == q.errFull && q.fullPolicy != FullReject {
+		left, err = q.putFull(value)
 	}
-	for i := range instance.elements {
-		instance.elements[i].putSeq = uint32(i)
-		instance.elements[i].getSeq = uint32(i)
+	err = q.fullError(err, 1)
+	q.report("Put", countOf(err), start, err)
+	return left, err
+}
+
+// 按队列已满策略填充数据。
+func (q *Queue[E]) putFull(value E) (uint32, error) {
+	switch q.fullPolicy {
+	case FullDropNewest:
+		if q.onDrop != nil {
+			q.onDrop(value)
+		}
+		return 0, nil
+	case FullDropOldest:
+		return q.putEvicting(value, q.onDrop)
+	case FullBlock:
+		return q.PutContext(context.Background(), value)
 	}
-	instance.elements[0].putSeq = capacity
-	instance.elements[0].getSeq = capacity
+	return 0, q.errFull
+}
 
-	return instance
+// 使用 WithMetricsSink 时返回操作开始时间，否则返回零值以省去取时间的开销。
+func (q *Queue[E]) startReport() time.Time {
+	if q.sink == nil {
+		return time.Time{}
+	}
+	return time.Now()
 }
 
-// Put 向队列尾部填充数据。返回剩余可填充数据个数。若队列已满返回错误 ErrQueueIsFull。
-func (q *Queue[E]) Put(value E) (uint32, error) {
-	position, _, left, err := q.acquirePut(1)
+// 将一次操作记录到 WithMetricsSink 设置的 sink。
+func (q *Queue[E]) report(op string, n uint32, start time.Time, err error) {
+	if q.sink != nil {
+		q.sink.Observe(op, n, time.Since(start), err)
+	}
+}
+
+// 将内部使用的队列已满错误转为返回给调用方的错误。使用 WithDetailedErrors 且未设置 WithErrors 时，
+// 返回携带当前容量和本次请求填充个数 requested 的 *FullError，其余错误原样返回。
+func (q *Queue[E]) fullError(err error, requested uint32) error {
+	if err != q.errFull || !q.opts.detailedErrors || q.opts.errFull != nil {
+		return err
+	}
+	return &FullError{Name: q.opts.name, Capacity: q.Cap(), Requested: requested}
+}
+
+// 向队列尾部填充数据，不考虑队列已满策略。
+func (q *Queue[E]) tryPut(value E) (uint32, error) {
+	_, left, err := q.tryPutSequenced(value)
+	return left, err
+}
+
+// 向队列尾部填充数据，不考虑队列已满策略。返回数据序号，剩余可填充数据个数。
+func (q *Queue[E]) tryPutSequenced(value E) (uint32, uint32, error) {
+	if q.isClosed() {
+		return 0, 0, ErrQueueIsClosed
+	}
+	if q.isPaused() {
+		return 0, 0, ErrQueuePaused
+	}
+	var weight uint32
+	if q.weightOf != nil {
+		if weight = q.weightOf(value); !q.acquireWeight(weight) {
+			return 0, 0, q.errFull
+		}
+	}
+	position, _, left, err := q.acquirePut(1, 1)
+	for err == q.errFull && q.grow() {
+		position, _, left, err = q.acquirePut(1, 1)
+	}
 	if err != nil {
-		return 0, err
+		q.releaseWeight(weight)
+		return 0, 0, err
 	}
 	q.put(position, value)
-	return left, nil
+	seq := q.sequenceOf(position)
+	q.leave()
+	return seq, left, nil
+}
+
+// PutReturningEvicted 向队列尾部填充数据，若队列已满则淘汰头部最旧的数据以腾出空位。
+// 返回按淘汰顺序排列的被淘汰数据，剩余可填充数据个数。
+//
+// 使用 WithWeights 创建的队列可能需淘汰多个数据才能容纳 value，多个协程同时填充时腾出的空位也可能被其他协程占用而需再次淘汰，
+// 每个被淘汰的数据都会返回。队列已关闭时返回 ErrQueueIsClosed，已暂停时返回 ErrQueuePaused，
+// 数据权重超过上限时返回 ErrQueueIsFull 且不淘汰任何数据，出错时 value 未被填充。
+func (q *Queue[E]) PutReturningEvicted(value E) ([]E, uint32, error) {
+	var evicted []E
+	left, err := q.putEvicting(value, func(val E) { evicted = append(evicted, val) })
+	return evicted, left, q.fullError(err, 1)
+}
+
+// 填充数据，队列已满时淘汰头部最旧的数据，每淘汰一个数据调用一次 onEvict。
+// 队列已关闭或暂停时返回相应错误。数据权重超过上限时不淘汰任何数据，直接返回 ErrQueueIsFull。
+func (q *Queue[E]) putEvicting(value E, onEvict func(E)) (uint32, error) {
+	if q.weightOf != nil && q.weightOf(value) > q.maxWeight {
+		return 0, q.errFull
+	}
+	for {
+		left, err := q.tryPut(value)
+		if err == nil || err == ErrQueueIsClosed || err == ErrQueuePaused {
+			return left, err
+		}
+		val, _, err := q.Get()
+		if err == nil {
+			if onEvict != nil {
+				onEvict(val)
+			}
+		} else if q.weightOf != nil {
+			return 0, q.errFull
+		}
+	}
 }
 
-// Get 取出队列头部数据。返回队列数据，队列剩余可取个数。当无数据可取时返回错误 ErrQueueIsEmpty。
+// ReserveSlot 认领队列尾部一个位置，返回该位置数据的指针以及提交函数。队列已满时返回 ErrQueueIsFull。
+// 调用方通过指针原地构造数据，再调用提交函数使其对取出方可见，以避免复制较大的数据。
+//
+// 指针仅在提交前有效，提交后不得再访问。认领后必须提交，否则取出方将一直等待该位置，后续数据也无法被取出。
+// 使用 WithWeights 创建的队列在提交时计入数据权重，但不检查是否超过上限。使用 WithResizable 创建的队列，提交前 Resize 将等待。
+func (q *Queue[E]) ReserveSlot() (*E, func(), error) {
+	position, _, _, err := q.acquirePut(1, 1)
+	if err != nil {
+		return nil, nil, q.fullError(err, 1)
+	}
+	elem := q.waitPut(position)
+	var once sync.Once
+	commit := func() {
+		once.Do(func() {
+			if q.weightOf != nil {
+				atomic.AddUint32(&q.weight, q.weightOf(elem.value))
+			}
+			q.publish(elem)
+			q.leave()
+		})
+	}
+	return &elem.value, commit, nil
+}
+
+// Get 取出队列头部数据。返回队列数据，队列剩余可取个数。当无数据可取时返回错误 ErrQueueIsEmpty，或 WithErrors 设置的错误。
+// 队列已关闭且无数据时返回 ErrQueueIsClosed。
 func (q *Queue[E]) Get() (E, uint32, error) {
+	start := q.startReport()
 	var val E
-	position, _, used, err := q.acquireGet(1)
+	position, _, used, err := q.acquireGet(1, 1)
 	if err != nil {
+		q.report("Get", 0, start, err)
 		return val, 0, err
 	}
 	val = q.get(position)
+	q.leave()
+	q.maybeShrink(used)
+	q.report("Get", 1, start, nil)
 	return val, used, nil
 }
 
 // PutEnough 向队列填充多个数据。返回实际填充数据个数，剩余可填充数据个数。
 func (q *Queue[E]) PutEnough(values ...E) (uint32, uint32) {
+	start := q.startReport()
+	accepted, left, _ := q.PutEnoughE(values...)
+	q.report("PutEnough", accepted, start, nil)
+	return accepted, left
+}
+
+// PutEnoughE 同 PutEnough，但一个数据也未填充时返回错误，以区分队列已满、已关闭等情况。
+// 队列已满或总权重不足时返回 ErrQueueIsFull。values 为空时不返回错误。
+func (q *Queue[E]) PutEnoughE(values ...E) (uint32, uint32, error) {
 	size := uint32(len(values))
 	if size == 0 {
-		return 0, q.Cap() - q.Len()
+		return 0, q.Cap() - q.Len(), nil
+	}
+	if q.weightOf != nil {
+		if size = q.acquireWeights(values); size == 0 {
+			return 0, 0, q.fullError(q.errFull, uint32(len(values)))
+		}
+	}
+	position, actualSize, left, err := q.acquirePut(1, size)
+	if q.weightOf != nil {
+		for _, v := range values[actualSize:size] {
+			q.releaseWeight(q.weightOf(v))
+		}
 	}
-	position, actualSize, left, err := q.acquirePut(size)
 	if err != nil {
-		return 0, 0
+		return 0, 0, q.fullError(err, uint32(len(values)))
 	}
 
-	for i, j := position, 0; i < position+actualSize; i, j = i+1, j+1 {
+	for i, j, end := position, 0, position+actualSize; i != end; i, j = i+1, j+1 {
 		q.put(i, values[j])
 	}
+	q.leave()
 
-	return actualSize, left
+	return actualSize, left, nil
+}
+
+// PutAtomic 向队列填充多个数据，要么全部填充，要么一个也不填充。空位不足时返回 ErrQueueIsFull。
+// 数据个数超过队列容量时永远无法填充。
+func (q *Queue[E]) PutAtomic(values ...E) error {
+	size := uint32(len(values))
+	if size == 0 {
+		return nil
+	}
+	if q.weightOf != nil {
+		if n := q.acquireWeights(values); n < size {
+			for _, v := range values[:n] {
+				q.releaseWeight(q.weightOf(v))
+			}
+			return q.fullError(q.errFull, size)
+		}
+	}
+	position, _, _, err := q.acquirePut(size, size)
+	if err != nil {
+		if q.weightOf != nil {
+			for _, v := range values {
+				q.releaseWeight(q.weightOf(v))
+			}
+		}
+		return q.fullError(err, size)
+	}
+	for i, v := range values {
+		q.put(position+uint32(i), v)
+	}
+	q.leave()
+	return nil
 }
 
 // GetEnough 从队列取出多个数据。返回队列队列数据，实际取出数据个数，剩余可取数据个数。
@@ -124,54 +480,341 @@ func (q *Queue[E]) GetEnough(size uint32) ([]E, uint32, uint32) {
 		return []E{}, 0, q.Cap() - q.Len()
 	}
 
-	position, actualSize, used, err := q.acquireGet(size)
+	start := q.startReport()
+	values, taken, remaining, _ := q.GetEnoughE(size)
+	q.report("GetEnough", taken, start, nil)
+	return values, taken, remaining
+}
+
+// GetEnoughE 从队列取出至多 size 个数据。返回队列数据，实际取出数据个数，剩余可取数据个数。
+//
+// 与 GetEnough 不同，remaining 始终表示剩余可取数据个数。一个数据也未取出时返回 ErrQueueIsEmpty，
+// 队列已关闭且无数据时返回 ErrQueueIsClosed。size 为零时返回空切片且不返回错误。
+func (q *Queue[E]) GetEnoughE(size uint32) ([]E, uint32, uint32, error) {
+	if size == 0 {
+		return []E{}, 0, q.Len(), nil
+	}
+
+	position, actualSize, used, err := q.acquireGet(1, size)
 	if err != nil {
-		return nil, 0, 0
+		return nil, 0, 0, err
 	}
 
 	res := make([]E, 0, actualSize)
-	for i := position; i < position+actualSize; i++ {
+	for i, end := position, position+actualSize; i != end; i++ {
 		res = append(res, q.get(i))
 	}
+	q.leave()
+	q.maybeShrink(used)
 
-	return res, actualSize, used
+	return res, actualSize, used, nil
 }
 
-// MustPut 向队列中塞数据，若队列已满将等待。返回剩余可填充数据个数。
-func (q *Queue[E]) MustPut(value E) uint32 {
-	var (
-		position, left uint32
-		err            error
-	)
+// GetInto 从队列取出至多 len(buf) 个数据，按先进先出顺序写入 buf。返回实际取出数据个数，剩余可取数据个数。
+//
+// 不分配内存，适合在循环中复用 buf 的消费者。
+func (q *Queue[E]) GetInto(buf []E) (uint32, uint32) {
+	if len(buf) == 0 {
+		return 0, q.Len()
+	}
+	size := uint32(len(buf))
+	if uint64(len(buf)) > uint64(^uint32(0)) {
+		size = ^uint32(0)
+	}
+	position, actualSize, used, err := q.acquireGet(1, size)
+	if err != nil {
+		return 0, 0
+	}
+	for i, j, end := position, 0, position+actualSize; i != end; i, j = i+1, j+1 {
+		buf[j] = q.get(i)
+	}
+	q.leave()
+	q.maybeShrink(used)
+	return actualSize, used
+}
+
+// GetEnoughFunc 从队列取出至多 n 个数据，并按先进先出顺序直接从槽位逐个调用 fn。返回实际取出数据个数，剩余可取数据个数。
+//
+// 不分配中间切片。fn 执行期间其他协程仍可取出后续数据，但本批后续数据的槽位在 fn 返回前不可被重新填充，fn 应尽快返回。
+func (q *Queue[E]) GetEnoughFunc(n uint32, fn func(E)) (uint32, uint32) {
+	if n == 0 {
+		return 0, q.Len()
+	}
+	position, actualSize, used, err := q.acquireGet(1, n)
+	if err != nil {
+		return 0, 0
+	}
+	for i, end := position, position+actualSize; i != end; i++ {
+		fn(q.get(i))
+	}
+	q.leave()
+	q.maybeShrink(used)
+	return actualSize, used
+}
+
+// GetAtomic 从队列取出 n 个数据，要么全部取出，要么一个也不取出。数据不足 n 个时返回 ErrNotEnough。
+// 队列已关闭且无数据时返回 ErrQueueIsClosed。
+func (q *Queue[E]) GetAtomic(n uint32) ([]E, error) {
+	if n == 0 {
+		return []E{}, nil
+	}
+	position, _, _, err := q.acquireGet(n, n)
+	if err == ErrQueueIsClosed {
+		return nil, err
+	}
+	if err != nil {
+		return nil, ErrNotEnough
+	}
+	res := make([]E, 0, n)
+	for i, end := position, position+n; i != end; i++ {
+		res = append(res, q.get(i))
+	}
+	q.leave()
+	return res, nil
+}
+
+// DrainEach 取出队列当前所有数据，并按先进先出顺序逐个调用 fn。返回取出数据个数。
+//
+// 只认领一次调用时刻已有的数据，且不分配结果切片，适合关闭前的清理工作。
+func (q *Queue[E]) DrainEach(fn func(E)) uint32 {
+	return q.drainBatch(fn, q.Cap())
+}
+
+// Drain 反复批量取出数据并按先进先出顺序逐个调用 fn，直至观察到队列为空。返回取出数据个数。
+//
+// 与 DrainEach 不同，调用期间新填充的数据也将被取出。不分配结果切片，适合关闭或刷新时使用。
+func (q *Queue[E]) Drain(fn func(E)) uint32 {
+	var total uint32
 	for {
-		position, _, left, err = q.acquirePut(1)
-		if err == nil {
-			break
+		n := q.drainBatch(fn, drainBatchSize)
+		if n == 0 {
+			return total
 		}
+		total += n
 	}
-	q.put(position, value)
-	return left
 }
 
-// MustGet 取出队列头部数据。，若队列无数据将等待。返回队列数据，队列剩余可取个数。
-func (q *Queue[E]) MustGet() (E, uint32) {
-	var (
-		position, used uint32
-		err            error
-	)
+// DrainContext 同 Drain，每批数据处理完后检查 ctx，ctx 结束时返回已取出数据个数和 ctx.Err()。
+func (q *Queue[E]) DrainContext(ctx context.Context, fn func(E)) (uint32, error) {
+	var total uint32
 	for {
-		position, _, used, err = q.acquireGet(1)
-		if err == nil {
-			break
+		if err := ctx.Err(); err != nil {
+			return total, err
 		}
+		n := q.drainBatch(fn, drainBatchSize)
+		if n == 0 {
+			return total, nil
+		}
+		total += n
+	}
+}
+
+// 认领至多 max 个数据，并按先进先出顺序逐个调用 fn。返回取出数据个数。
+func (q *Queue[E]) drainBatch(fn func(E), max uint32) uint32 {
+	position, size, _, err := q.acquireGet(1, max)
+	if err != nil {
+		return 0
+	}
+	for i, end := position, position+size; i != end; i++ {
+		fn(q.get(i))
+	}
+	q.leave()
+	return size
+}
+
+// Clear 丢弃队列当前所有数据，并清零其所在位置。返回丢弃数据个数。
+//
+// 一次认领调用时刻已有的全部数据，不会与并发的取出操作重复处理，之后填充的数据不受影响。
+func (q *Queue[E]) Clear() uint32 {
+	return q.DrainEach(func(E) {})
+}
+
+// MustPut 向队列中塞数据，若队列已满将等待，默认挂起协程直至有数据被取出。返回剩余可填充数据个数。
+// 若等待超过 SetMaxBlockDuration 设置的时长，将以 ErrBlockTimeout 触发 panic；队列已关闭时，将以 ErrQueueIsClosed 触发 panic。
+func (q *Queue[E]) MustPut(value E) uint32 {
+	left, err := q.PutContext(context.Background(), value)
+	if err != nil {
+		panic(err)
+	}
+	return left
+}
+
+// MustPutEnough 向队列填充多个数据，空位不足时等待，直至全部填充。
+// 若等待超过 SetMaxBlockDuration 设置的时长，将以 ErrBlockTimeout 触发 panic；队列已关闭时，将以 ErrQueueIsClosed 触发 panic。
+func (q *Queue[E]) MustPutEnough(values ...E) {
+	if _, err := q.PutEnoughContext(context.Background(), values...); err != nil {
+		panic(err)
+	}
+}
+
+// MustGetEnough 从队列取出 n 个数据，数据不足时等待，直至取满。
+// 若等待超过 SetMaxBlockDuration 设置的时长，将以 ErrBlockTimeout 触发 panic；队列已关闭且无数据时，将以 ErrQueueIsClosed 触发 panic。
+func (q *Queue[E]) MustGetEnough(n uint32) []E {
+	res, err := q.GetEnoughContext(context.Background(), n)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// MustGet 取出队列头部数据。，若队列无数据将等待，默认挂起协程直至有数据填充。返回队列数据，队列剩余可取个数。
+// 若等待超过 SetMaxBlockDuration 设置的时长，将以 ErrBlockTimeout 触发 panic；队列已关闭且无数据时，将以 ErrQueueIsClosed 触发 panic。
+func (q *Queue[E]) MustGet() (E, uint32) {
+	val, used, err := q.GetContext(context.Background())
+	if err != nil {
+		panic(err)
 	}
-	val := q.get(position)
 	return val, used
 }
 
-// Cap 返回队列长度。
+// SetMaxBlockDuration 设置阻塞操作最长等待时长，作为防止永久阻塞的兜底。d 小于等于零表示不限制。
+// 仅对此后开始的阻塞操作生效。
+func (q *Queue[E]) SetMaxBlockDuration(d time.Duration) {
+	atomic.StoreInt64(&q.maxBlock, int64(d))
+}
+
+// Compact 将队列头尾位置归零，并重新整理所有位置序号，数据保持原有顺序。可在业务低峰期调用以回收序号空间。
+//
+// 调用时不得有任何协程在操作该队列，否则队列数据将错乱。
+func (q *Queue[E]) Compact() {
+	q.rebuild(q.capacity)
+}
+
+// RemoveWhere 移除所有使 fn 返回 true 的数据，其余数据保持原有顺序。返回移除数据个数。
+// 可在填充和取出并发进行时调用，如取消排队中的任务。调用期间填充和取出操作将等待。
+//
+// 被移除的数据视为已取出，剩余数据的序号将随之改变。需使用 WithResizable 或 WithAutoGrow 创建队列，否则返回 ErrNotResizable。
+func (q *Queue[E]) RemoveWhere(fn func(E) bool) (uint32, error) {
+	if q.gate == nil {
+		return 0, ErrNotResizable
+	}
+	q.gate.Lock()
+	removed := q.rebuildWhere(q.capacity, fn)
+	q.gate.Unlock()
+	if removed > 0 {
+		q.notFull.notify()
+	}
+	return removed, nil
+}
+
+// Resize 调整队列容量，数据保持原有顺序。capacity 调整规则同 New，使用 WithExactCapacity 时按 capacity 限制数据个数。
+// 队列数据个数超过新容量时返回 ErrQueueIsFull，未使用 WithResizable 创建时返回 ErrNotResizable。
+//
+// 调整期间填充和取出操作将等待。持有 ReserveSlot 认领的位置的协程不可调用 Resize，否则将死锁。
+func (q *Queue[E]) Resize(capacity uint32) error {
+	if q.gate == nil {
+		return ErrNotResizable
+	}
+	return q.resize(capacity, 0)
+}
+
+// 调整队列容量。expect 不为零时，仅当当前容量等于 expect 时调整，以免多个协程重复调整。
+func (q *Queue[E]) resize(capacity, expect uint32) error {
+	limit := q.limitOf(capacity)
+
+	q.gate.Lock()
+	if expect != 0 && q.Cap() != expect {
+		q.gate.Unlock()
+		return nil
+	}
+	if q.Len() > limit {
+		q.gate.Unlock()
+		return ErrQueueIsFull
+	}
+	q.rebuild(roundCapacity(capacity))
+	atomic.StoreUint32(&q.limit, limit)
+	q.gate.Unlock()
+
+	q.notFull.notify()
+	return nil
+}
+
+// 按新的容量重建元素数组，将头尾位置归零并重新整理位置序号。
+func (q *Queue[E]) rebuild(capacity uint32) {
+	q.rebuildWhere(capacity, nil)
+}
+
+// 同 rebuild，并移除 remove 返回 true 的数据。返回移除数据个数。
+func (q *Queue[E]) rebuildWhere(capacity uint32, remove func(E) bool) uint32 {
+	head := atomic.LoadUint32(&q.head)
+	tail := atomic.LoadUint32(&q.tail)
+	values := make([]E, 0, q.usedSize(tail, head))
+	var removed uint32
+	for i := head + 1; i != tail+1; i++ {
+		val := q.slot(i).value
+		if remove != nil && remove(val) {
+			if q.weightOf != nil {
+				q.releaseWeight(q.weightOf(val))
+			}
+			removed++
+			continue
+		}
+		values = append(values, val)
+	}
+	if q.consumeLog != nil {
+		// 被移除的数据视为已取出，其序号为剩余数据重新编号前空出的部分。
+		for i, base := uint32(1), q.sequenceOf(head); i <= removed; i++ {
+			q.consumeLog.record(base + i)
+		}
+	}
+
+	if capacity != q.capacity {
+		q.allocate(capacity)
+	} else {
+		q.reset()
+	}
+	q.place(values)
+
+	atomic.AddUint32(&q.seqOffset, head+removed)
+	atomic.StoreUint32(&q.head, 0)
+	atomic.StoreUint32(&q.tail, uint32(len(values)))
+	return removed
+}
+
+// 将 values 依次放入已重置的元素数组的位置1至 len(values)，不修改头尾位置。调用方须持有 gate 写锁。
+func (q *Queue[E]) place(values []E) {
+	for i, v := range values {
+		position := uint32(i) + 1
+		elem := q.slot(position)
+		elem.value = v
+		elem.putSeq = position + q.capacity
+		elem.getSeq = position
+	}
+}
+
+// 按容量分配元素数组。使用 WithSegmentSize 且容量大于段长度时，分段分配。
+// 使用 PadSlots 时每个元素占用 1<<strideShift 个位置，其余位置仅作填充。
+func (q *Queue[E]) allocate(capacity uint32) {
+	q.capacity, q.mask = capacity, capacity-1
+	q.elements, q.segments = nil, nil
+	if size := q.segmentSize; size > 0 && size < capacity {
+		q.segmentShift = uint32(bits.TrailingZeros32(size))
+		q.segments = make([][]element[E], capacity/size)
+		for i := range q.segments {
+			q.segments[i] = make([]element[E], uint(size)<<q.strideShift)
+		}
+	} else {
+		q.elements = make([]element[E], uint(capacity)<<q.strideShift)
+	}
+	q.reset()
+}
+
+// 清空所有元素，并将位置序号恢复为初始值。
+func (q *Queue[E]) reset() {
+	var empty E
+	for i := uint32(0); i < q.capacity; i++ {
+		elem := q.element(i)
+		elem.value = empty
+		elem.putSeq = i
+		elem.getSeq = i
+	}
+	q.element(0).putSeq = q.capacity
+	q.element(0).getSeq = q.capacity
+}
+
+// Cap 返回队列长度。使用 WithExactCapacity 时为创建时指定的长度。
 func (q *Queue[E]) Cap() uint32 {
-	return q.capacity
+	return atomic.LoadUint32(&q.limit)
 }
 
 // Len 返回队列数据个数。
@@ -186,13 +829,158 @@ func (q *Queue[E]) IsEmpty() bool {
 
 // IsFull 判断队列是否已满。
 func (q *Queue[E]) IsFull() bool {
-	return atomic.LoadUint32(&q.tail)-atomic.LoadUint32(&q.head) == q.capacity
+	return atomic.LoadUint32(&q.tail)-atomic.LoadUint32(&q.head) >= q.Cap()
+}
+
+// Close 关闭队列，并停止队列的后台协程。可重复调用。
+//
+// 关闭后填充数据返回 ErrQueueIsClosed；取出数据仍可取出剩余数据，取尽后返回 ErrQueueIsClosed。阻塞中的操作将立即被唤醒。
+func (q *Queue[E]) Close() {
+	q.closeOnce.Do(func() {
+		atomic.StoreUint32(&q.closed, 1)
+		close(q.done)
+		q.checkDrained()
+		q.notEmpty.notify()
+		q.notFull.notify()
+	})
+}
+
+// WaitDrained 返回一个通道，当队列已关闭且数据均已被取出时关闭该通道。
+func (q *Queue[E]) WaitDrained() <-chan struct{} {
+	return q.drained
+}
+
+// CloseAndDrain 关闭队列，并按先进先出顺序将剩余数据逐个交给 fn，直至取尽或 ctx 结束。返回未取出的数据个数。
+//
+// 适合进程退出前的清理，关闭后新的填充将返回 ErrQueueIsClosed，已入队的数据不会丢失。
+func (q *Queue[E]) CloseAndDrain(ctx context.Context, fn func(E)) uint32 {
+	q.Close()
+	for ctx.Err() == nil {
+		val, _, err := q.Get()
+		if err != nil {
+			return 0
+		}
+		fn(val)
+	}
+	return q.Len()
+}
+
+// Pause 暂停填充数据，取出数据不受影响。可重复调用。
+//
+// 暂停期间 Put 等非阻塞填充返回 ErrQueuePaused。PutContext、MustPut 等阻塞填充默认同样返回 ErrQueuePaused，
+// 使用 WithBlockWhenPaused 时则等待至 Resume。
+func (q *Queue[E]) Pause() {
+	atomic.StoreUint32(&q.paused, 1)
+}
+
+// Resume 恢复填充数据，并唤醒因暂停而等待的填充操作。可重复调用。
+func (q *Queue[E]) Resume() {
+	if atomic.CompareAndSwapUint32(&q.paused, 1, 0) {
+		q.notFull.notify()
+	}
+}
+
+// IsPaused 队列是否已暂停填充。
+func (q *Queue[E]) IsPaused() bool {
+	return q.isPaused()
+}
+
+// 返回容量 capacity 对应的数据个数上限。
+func (q *Queue[E]) limitOf(capacity uint32) uint32 {
+	if q.opts.exactCapacity && capacity > 0 {
+		return capacity
+	}
+	return roundCapacity(capacity)
+}
+
+// 使用 WithAutoGrow 创建的队列已满时将容量加倍，不超过上限。返回是否可重试填充。
+func (q *Queue[E]) grow() bool {
+	capacity := q.Cap()
+	if q.growMax == 0 || capacity >= q.growMax {
+		return false
+	}
+	next := capacity * 2
+	if next > q.growMax || next < capacity {
+		next = q.growMax
+	}
+	return q.resize(next, capacity) == nil
+}
+
+// 使用 WithAutoGrow 创建的队列数据个数持续低于容量四分之一时将容量减半，不低于创建时的容量。used 取出后的数据个数。
+func (q *Queue[E]) maybeShrink(used uint32) {
+	if q.shrinkAfter <= 0 {
+		return
+	}
+	capacity := q.Cap()
+	if capacity <= q.shrinkMin || used >= capacity/4 {
+		atomic.StoreInt64(&q.lowSince, 0)
+		return
+	}
+	now := time.Now().UnixNano()
+	since := atomic.LoadInt64(&q.lowSince)
+	if since == 0 {
+		atomic.CompareAndSwapInt64(&q.lowSince, 0, now)
+		return
+	}
+	if now-since < q.shrinkAfter || !atomic.CompareAndSwapInt64(&q.lowSince, since, 0) {
+		return
+	}
+	next := capacity / 2
+	if next < q.shrinkMin {
+		next = q.shrinkMin
+	}
+	_ = q.resize(next, capacity)
+}
+
+// 进入槽位访问区间。使用 WithResizable 创建的队列在 Resize 期间将等待。
+func (q *Queue[E]) enter() {
+	if q.gate != nil {
+		q.gate.RLock()
+	}
+}
+
+// 离开槽位访问区间。
+func (q *Queue[E]) leave() {
+	if q.gate != nil {
+		q.gate.RUnlock()
+	}
+}
+
+func (q *Queue[E]) isPaused() bool {
+	return atomic.LoadUint32(&q.paused) == 1
+}
+
+func (q *Queue[E]) isClosed() bool {
+	return atomic.LoadUint32(&q.closed) == 1
+}
+
+func (q *Queue[E]) checkDrained() {
+	if q.isClosed() && q.IsEmpty() {
+		q.drainOnce.Do(func() { close(q.drained) })
+	}
+}
+
+// Name 返回 WithName 设置的队列名称。
+func (q *Queue[E]) Name() string {
+	return q.opts.name
 }
 
 // String 返回队列字符串表示形式值。
 func (q *Queue[E]) String() string {
-	return fmt.Sprintf(`Queue: Head:%d Tail:%d Len:%d Cap:%d`,
-		atomic.LoadUint32(&q.head), atomic.LoadUint32(&q.tail), q.Len(), q.Cap())
+	name := "Queue"
+	if q.opts.name != "" {
+		name += "(" + q.opts.name + ")"
+	}
+	return fmt.Sprintf(`%s: Head:%d Tail:%d Len:%d Cap:%d`,
+		name, atomic.LoadUint32(&q.head), atomic.LoadUint32(&q.tail), q.Len(), q.Cap())
+}
+
+func (q *Queue[E]) blockDeadline() time.Time {
+	d := atomic.LoadInt64(&q.maxBlock)
+	if d <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(time.Duration(d))
 }
 
 func (q *Queue[E]) usedSize(tail, head uint32) uint32 {
@@ -200,66 +988,167 @@ func (q *Queue[E]) usedSize(tail, head uint32) uint32 {
 }
 
 func (q *Queue[E]) leftSize(tail, head uint32) uint32 {
-	return q.capacity - q.usedSize(tail, head)
+	return q.limit - q.usedSize(tail, head)
 }
 
-func (q *Queue[E]) acquirePut(size uint32) (uint32, uint32, uint32, error) {
+// 认领 least 至 size 个可填充位置，不足 least 个时返回错误。返回起始位置，认领个数，剩余可填充个数。
+func (q *Queue[E]) acquirePut(least, size uint32) (uint32, uint32, uint32, error) {
 	var head, tail, left uint32
 
+	q.enter()
 	for {
 		head = atomic.LoadUint32(&q.head)
 		tail = atomic.LoadUint32(&q.tail)
 		left = q.leftSize(tail, head)
-		if left == 0 {
-			return 0, 0, 0, ErrQueueIsFull
+		if q.isClosed() {
+			q.leave()
+			return 0, 0, 0, ErrQueueIsClosed
+		}
+		if q.isPaused() {
+			q.leave()
+			return 0, 0, 0, ErrQueuePaused
+		}
+		if left < least {
+			if q.counters != nil {
+				atomic.AddUint64(&q.counters.fullFailures, 1)
+			}
+			q.leave()
+			return 0, 0, 0, q.errFull
 		}
 		if size > left {
 			size = left
 		}
 		if atomic.CompareAndSwapUint32(&q.tail, tail, tail+size) {
+			q.updateMaxLen(q.limit - left + size)
+			if q.ewmaAlpha > 0 {
+				q.updateFillEWMA(q.limit - left + size)
+			}
+			if q.counters != nil {
+				atomic.AddUint64(&q.counters.puts, uint64(size))
+			}
 			return tail + 1, size, left - size, nil
 		}
+		if q.counters != nil {
+			atomic.AddUint64(&q.counters.casRetries, 1)
+		}
 		runtime.Gosched()
 	}
 }
 
-func (q *Queue[E]) acquireGet(size uint32) (uint32, uint32, uint32, error) {
+// 认领 least 至 size 个可取出位置，不足 least 个时返回错误。返回起始位置，认领个数，剩余可取出个数。
+func (q *Queue[E]) acquireGet(least, size uint32) (uint32, uint32, uint32, error) {
 	var head, tail, used uint32
 
+	q.enter()
 	for {
 		head = atomic.LoadUint32(&q.head)
 		tail = atomic.LoadUint32(&q.tail)
 		used = q.usedSize(tail, head)
-		if used == 0 {
-			return 0, 0, 0, ErrQueueIsEmpty
+		if used < least {
+			q.leave()
+			if used == 0 && q.isClosed() {
+				return 0, 0, 0, ErrQueueIsClosed
+			}
+			if q.counters != nil {
+				atomic.AddUint64(&q.counters.emptyFailures, 1)
+			}
+			return 0, 0, 0, q.errEmpty
 		}
 		if size > used {
 			size = used
 		}
 		if atomic.CompareAndSwapUint32(&q.head, head, head+size) {
+			if q.ewmaAlpha > 0 {
+				q.updateFillEWMA(used - size)
+			}
+			if q.counters != nil {
+				atomic.AddUint64(&q.counters.gets, uint64(size))
+			}
 			return head + 1, size, used - size, nil
 		}
+		if q.counters != nil {
+			atomic.AddUint64(&q.counters.casRetries, 1)
+		}
 		runtime.Gosched()
 	}
 }
 
+// positionToIndex 返回位置对应的元素下标。
+//
+// 位置从 1 开始计数：acquirePut 与 acquireGet 返回的起始位置为 tail+1 与 head+1，认领 size 个位置后，
+// 有效位置为 [起始位置, 起始位置+size)。位置按 uint32 回绕，因容量为 2 的幂，回绕前后的位置仍按低位映射到同一下标。
+// 遍历位置时须使用 i != end 作为终止条件，不可使用 i < end。
+func (q *Queue[E]) positionToIndex(position uint32) uint32 {
+	return position & q.mask
+}
+
+// 返回位置对应的元素。
+func (q *Queue[E]) slot(position uint32) *element[E] {
+	return q.element(q.positionToIndex(position))
+}
+
+// 返回下标对应的元素。
+func (q *Queue[E]) element(index uint32) *element[E] {
+	if q.segments != nil {
+		return &q.segments[index>>q.segmentShift][uint(index&(q.segmentSize-1))<<q.strideShift]
+	}
+	return &q.elements[uint(index)<<q.strideShift]
+}
+
 func (q *Queue[E]) get(position uint32) E {
-	elem := &q.elements[position&q.mask]
-	for !(position == atomic.LoadUint32(&elem.getSeq) && position == atomic.LoadUint32(&elem.putSeq)-q.capacity) {
-		runtime.Gosched()
+	if q.callers != nil {
+		q.callers.trackConsumer()
 	}
+	elem := q.slot(position)
+	// 以 CAS 将 getSeq 改为 position-1 锁定该位置，与 Peek 互斥。
+	for i := 0; !(position == atomic.LoadUint32(&elem.putSeq)-q.capacity &&
+		atomic.CompareAndSwapUint32(&elem.getSeq, position, position-1)); i++ {
+		q.strategy.Wait(i)
+	}
+	return q.take(position, elem)
+}
+
+// 取出已锁定位置上的数据，并使该位置可被下一轮填充。
+func (q *Queue[E]) take(position uint32, elem *element[E]) E {
 	val := elem.value
-	var empty E
-	elem.value = empty
-	_ = atomic.AddUint32(&elem.getSeq, q.capacity)
+	if !q.opts.noZeroing {
+		var empty E
+		elem.value = empty
+	}
+	atomic.StoreUint32(&elem.getSeq, position+q.capacity)
+	if q.weightOf != nil {
+		q.releaseWeight(q.weightOf(val))
+	}
+	if q.consumeLog != nil {
+		q.consumeLog.record(q.sequenceOf(position))
+	}
+	if q.isClosed() {
+		q.checkDrained()
+	}
+	q.notFull.notify()
 	return val
 }
 
 func (q *Queue[E]) put(position uint32, value E) {
-	elem := &q.elements[position&q.mask]
-	for !(position == atomic.LoadUint32(&elem.getSeq) && position == atomic.LoadUint32(&elem.putSeq)) {
-		runtime.Gosched()
+	if q.callers != nil {
+		q.callers.trackProducer()
 	}
+	elem := q.waitPut(position)
 	elem.value = value
+	q.publish(elem)
+}
+
+// 等待位置可写入，返回对应元素。
+func (q *Queue[E]) waitPut(position uint32) *element[E] {
+	elem := q.slot(position)
+	for i := 0; !(position == atomic.LoadUint32(&elem.getSeq) && position == atomic.LoadUint32(&elem.putSeq)); i++ {
+		q.strategy.Wait(i)
+	}
+	return elem
+}
+
+// 使写入的元素对取出方可见。
+func (q *Queue[E]) publish(elem *element[E]) {
 	_ = atomic.AddUint32(&elem.putSeq, q.capacity)
+	q.notEmpty.notify()
 }
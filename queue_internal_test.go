@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"math"
+	"testing"
+)
+
+// 将空队列的头尾移动到 position，并设置各元素对应的序号。
+func moveTo[E any](q *Queue[E], position uint32) {
+	q.head, q.tail = position, position
+	for i := uint32(1); i <= q.capacity; i++ {
+		elem := q.slot(position + i)
+		elem.getSeq = position + i
+		elem.putSeq = position + i
+	}
+}
+
+func TestPositionToIndex(t *testing.T) {
+	q := New[int](8)
+	cases := []struct{ position, index uint32 }{
+		{1, 1}, {7, 7}, {8, 0}, {9, 1}, {16, 0},
+		{math.MaxUint32 - 1, 6}, {math.MaxUint32, 7}, {0, 0}, {1, 1},
+	}
+	for _, c := range cases {
+		if index := q.positionToIndex(c.position); index != c.index {
+			t.Fatalf("positionToIndex(%d) = %d, want %d", c.position, index, c.index)
+		}
+	}
+
+	position, size, _, err := q.acquirePut(1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if position != 1 || size != 3 {
+		t.Fatal("first put position != 1")
+	}
+	position, _, _, err = q.acquireGet(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if position != 1 {
+		t.Fatal("first get position != 1")
+	}
+}
+
+func TestEnoughAcrossWrap(t *testing.T) {
+	q := New[int](8)
+	moveTo(q, math.MaxUint32-2)
+	size, left := q.PutEnough(1, 2, 3, 4, 5, 6)
+	if size != 6 || left != 2 {
+		t.Fatal("size != 6 || left != 2")
+	}
+	if q.tail != 3 {
+		t.Fatal("tail != 3")
+	}
+	vals, size, used := q.GetEnough(8)
+	if size != 6 || used != 0 {
+		t.Fatal("size != 6 || used != 0")
+	}
+	for i, v := range vals {
+		if v != i+1 {
+			t.Fatal("v != i+1")
+		}
+	}
+
+	moveTo(q, math.MaxUint32)
+	for i := 0; i < 8; i++ {
+		if _, err := q.Put(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 8; i++ {
+		if val, _, _ := q.Get(); val != i {
+			t.Fatal("val != i")
+		}
+	}
+}
@@ -13,9 +13,13 @@
 package safe_queue_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"math"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -255,3 +259,1004 @@ func TestUint32Overflow(t *testing.T) {
 	}
 	// t.Log(q)
 }
+
+func TestDrainEach(t *testing.T) {
+	q := queue.New[*int](8)
+	if n := q.DrainEach(func(*int) { t.Fatal("fn called on empty queue") }); n != 0 {
+		t.Fatal("n != 0")
+	}
+	values := make([]*int, 5)
+	for i := range values {
+		v := i
+		values[i] = &v
+		q.MustPut(&v)
+	}
+	seen := make(map[*int]int)
+	var order []int
+	n := q.DrainEach(func(v *int) {
+		seen[v]++
+		order = append(order, *v)
+	})
+	if n != 5 {
+		t.Fatal("n != 5")
+	}
+	for _, v := range values {
+		if seen[v] != 1 {
+			t.Fatal("seen[v] != 1")
+		}
+	}
+	for i, v := range order {
+		if v != i {
+			t.Fatal("v != i")
+		}
+	}
+	if !q.IsEmpty() {
+		t.Fatal("queue is not empty")
+	}
+}
+
+func TestMaxBlockDuration(t *testing.T) {
+	q := queue.New[int](2)
+	q.SetMaxBlockDuration(time.Millisecond * 50)
+
+	start := time.Now()
+	func() {
+		defer func() {
+			if p := recover(); p != queue.ErrBlockTimeout {
+				t.Fatal("p != ErrBlockTimeout")
+			}
+		}()
+		q.MustGet()
+	}()
+	if elapsed := time.Since(start); elapsed < time.Millisecond*50 || elapsed > time.Second {
+		t.Fatalf("elapsed %v", elapsed)
+	}
+
+	q.MustPut(1)
+	q.MustPut(2)
+	func() {
+		defer func() {
+			if p := recover(); p != queue.ErrBlockTimeout {
+				t.Fatal("p != ErrBlockTimeout")
+			}
+		}()
+		q.MustPut(3)
+	}()
+
+	q.SetMaxBlockDuration(0)
+	if val, _ := q.MustGet(); val != 1 {
+		t.Fatal("val != 1")
+	}
+}
+
+func TestCompact(t *testing.T) {
+	q := queue.New[int](8)
+	for i := 0; i < 1000; i++ {
+		q.MustPut(i)
+		q.MustGet()
+	}
+	q.PutEnough(1, 2, 3, 4, 5)
+	q.GetEnough(2)
+	q.Compact()
+	if q.String() != "Queue: Head:0 Tail:3 Len:3 Cap:8" {
+		t.Fatal(q.String())
+	}
+
+	size, left := q.PutEnough(6, 7, 8, 9, 10, 11)
+	if size != 5 {
+		t.Fatal("size != 5")
+	}
+	if left != 0 {
+		t.Fatal("left != 0")
+	}
+	vals, size, _ := q.GetEnough(8)
+	if size != 8 {
+		t.Fatal("size != 8")
+	}
+	for i, v := range vals {
+		if v != i+3 {
+			t.Fatal("v != i+3")
+		}
+	}
+
+	q.Compact()
+	if !q.IsEmpty() {
+		t.Fatal("queue is not empty")
+	}
+	q.MustPut(1)
+	if val, _ := q.MustGet(); val != 1 {
+		t.Fatal("val != 1")
+	}
+}
+
+func TestPutReturningEvicted(t *testing.T) {
+	q := queue.New[int](4)
+	for i := 0; i < 4; i++ {
+		evicted, left, err := q.PutReturningEvicted(i)
+		if err != nil || len(evicted) != 0 {
+			t.Fatal("evicted")
+		}
+		if left != uint32(3-i) {
+			t.Fatal("left != 3-i")
+		}
+	}
+	for i := 4; i < 10; i++ {
+		evicted, left, err := q.PutReturningEvicted(i)
+		if err != nil || len(evicted) != 1 {
+			t.Fatal("len(evicted) != 1")
+		}
+		if evicted[0] != i-4 {
+			t.Fatal("evicted != i-4")
+		}
+		if left != 0 {
+			t.Fatal("left != 0")
+		}
+	}
+	vals, _, _ := q.GetEnough(4)
+	for i, v := range vals {
+		if v != i+6 {
+			t.Fatal("v != i+6")
+		}
+	}
+
+	dropped := 0
+	q = queue.New[int](4, queue.WithWeights(func(v int) uint32 { return uint32(v) }, 5),
+		queue.WithFullPolicy(queue.FullDropOldest, func(int) { dropped++ }))
+	q.PutEnough(1, 2)
+	if evicted, _, err := q.PutReturningEvicted(6); err != queue.ErrQueueIsFull || len(evicted) != 0 || q.Len() != 2 {
+		t.Fatal("oversized value evicted data")
+	}
+	if _, err := q.Put(6); err != queue.ErrQueueIsFull || dropped != 0 || q.Len() != 2 {
+		t.Fatal("oversized value evicted data")
+	}
+	if evicted, _, err := q.PutReturningEvicted(3); err != nil || len(evicted) != 1 || evicted[0] != 1 || q.Len() != 2 {
+		t.Fatal("evicted != [1]")
+	}
+	if evicted, _, err := q.PutReturningEvicted(5); err != nil || len(evicted) != 2 || evicted[0] != 2 || evicted[1] != 3 {
+		t.Fatal("evicted != [2 3]")
+	}
+	if val, _, _ := q.Get(); val != 5 {
+		t.Fatal("val != 5")
+	}
+
+	q = queue.New[int](2)
+	q.PutEnough(1, 2)
+	q.Pause()
+	if evicted, _, err := q.PutReturningEvicted(3); err != queue.ErrQueuePaused || len(evicted) != 0 || q.Len() != 2 {
+		t.Fatal("err != ErrQueuePaused")
+	}
+	q.Resume()
+	q.Close()
+	if evicted, _, err := q.PutReturningEvicted(3); err != queue.ErrQueueIsClosed || len(evicted) != 0 {
+		t.Fatal("err != ErrQueueIsClosed")
+	}
+}
+
+func TestWaitDrained(t *testing.T) {
+	q := queue.New[int](8)
+	q.PutEnough(1, 2, 3)
+	q.Close()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-q.WaitDrained():
+			t.Fatal("drained before last Get")
+		default:
+		}
+		q.MustGet()
+	}
+	select {
+	case <-q.WaitDrained():
+	case <-time.After(time.Second):
+		t.Fatal("not drained after last Get")
+	}
+
+	q = queue.New[int](8)
+	q.Close()
+	select {
+	case <-q.WaitDrained():
+	default:
+		t.Fatal("empty closed queue not drained")
+	}
+}
+
+func TestWithErrors(t *testing.T) {
+	errFull := fmt.Errorf("orders: %w", queue.ErrQueueIsFull)
+	errEmpty := fmt.Errorf("orders: %w", queue.ErrQueueIsEmpty)
+	q := queue.New[int](2, queue.WithErrors(errFull, errEmpty))
+	if _, _, err := q.Get(); err != errEmpty {
+		t.Fatal("err != errEmpty")
+	}
+	q.PutEnough(1, 2)
+	_, err := q.Put(3)
+	if err != errFull {
+		t.Fatal("err != errFull")
+	}
+	if !errors.Is(err, queue.ErrQueueIsFull) {
+		t.Fatal("err is not ErrQueueIsFull")
+	}
+
+	q = queue.New[int](2, queue.WithErrors(errFull, nil))
+	if _, _, err := q.Get(); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+}
+
+func TestPutAtomic(t *testing.T) {
+	q := queue.New[int](8)
+	if err := q.PutAtomic(1, 2, 3, 4, 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.PutAtomic(6, 7, 8, 9); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	if q.Len() != 5 {
+		t.Fatal("Len != 5")
+	}
+	if err := q.PutAtomic(6, 7, 8); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.PutAtomic(); err != nil {
+		t.Fatal(err)
+	}
+	vals, size, _ := q.GetEnough(8)
+	if size != 8 {
+		t.Fatal("size != 8")
+	}
+	for i, v := range vals {
+		if v != i+1 {
+			t.Fatal("v != i+1")
+		}
+	}
+
+	q = queue.New[int](8, queue.WithWeights(func(v int) uint32 { return uint32(v) }, 10))
+	if err := q.PutAtomic(3, 4, 5); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	if q.Weight() != 0 || q.Len() != 0 {
+		t.Fatal("partial batch placed")
+	}
+}
+
+func TestGetAtomic(t *testing.T) {
+	q := queue.New[int](8)
+	if _, err := q.GetAtomic(1); err != queue.ErrNotEnough {
+		t.Fatal("err != ErrNotEnough")
+	}
+	q.PutEnough(1, 2, 3)
+	vals, err := q.GetAtomic(4)
+	if err != queue.ErrNotEnough {
+		t.Fatal("err != ErrNotEnough")
+	}
+	if vals != nil {
+		t.Fatal("vals != nil")
+	}
+	if q.Len() != 3 {
+		t.Fatal("Len != 3")
+	}
+	vals, err = q.GetAtomic(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 2 || vals[0] != 1 || vals[1] != 2 {
+		t.Fatal("vals != [1 2]")
+	}
+	if q.Len() != 1 {
+		t.Fatal("Len != 1")
+	}
+}
+
+func TestReserveSlot(t *testing.T) {
+	type frame struct {
+		id      int
+		payload [256]byte
+	}
+	q := queue.New[frame](2)
+	ptr, commit, err := q.ReserveSlot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ptr.id = 1
+	ptr.payload[0] = 'a'
+
+	got := make(chan frame)
+	go func() {
+		val, _ := q.MustGet()
+		got <- val
+	}()
+	select {
+	case <-got:
+		t.Fatal("consumer got uncommitted slot")
+	case <-time.After(time.Millisecond * 20):
+	}
+	commit()
+	commit()
+	val := <-got
+	if val.id != 1 || val.payload[0] != 'a' {
+		t.Fatal("val mismatch")
+	}
+
+	q.MustPut(frame{id: 2})
+	q.MustPut(frame{id: 3})
+	if _, _, err = q.ReserveSlot(); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+}
+
+func TestClose(t *testing.T) {
+	q := queue.New[int](8)
+	q.PutEnough(1, 2)
+	q.Close()
+	q.Close()
+	if _, err := q.Put(3); err != queue.ErrQueueIsClosed {
+		t.Fatal("err != ErrQueueIsClosed")
+	}
+	if err := q.PutAtomic(3); err != queue.ErrQueueIsClosed {
+		t.Fatal("err != ErrQueueIsClosed")
+	}
+	for i := 1; i <= 2; i++ {
+		if val, _, err := q.Get(); err != nil || val != i {
+			t.Fatal("remaining data lost")
+		}
+	}
+	if _, _, err := q.Get(); err != queue.ErrQueueIsClosed {
+		t.Fatal("err != ErrQueueIsClosed")
+	}
+
+	q = queue.New[int](1)
+	errs := make(chan error, 2)
+	go func() {
+		_, _, err := q.GetContext(context.Background())
+		errs <- err
+	}()
+	go func() {
+		defer func() { errs <- recover().(error) }()
+		q.MustGet()
+	}()
+	time.Sleep(time.Millisecond * 20)
+	q.Close()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if err != queue.ErrQueueIsClosed {
+				t.Fatal("err != ErrQueueIsClosed")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("blocked Get not woken by Close")
+		}
+	}
+}
+
+func TestCloseAndDrain(t *testing.T) {
+	q := queue.New[int](8)
+	q.PutEnough(1, 2, 3, 4)
+	var got []int
+	if left := q.CloseAndDrain(context.Background(), func(v int) { got = append(got, v) }); left != 0 {
+		t.Fatal("left != 0")
+	}
+	if len(got) != 4 || got[0] != 1 || got[3] != 4 {
+		t.Fatal("got != [1 2 3 4]")
+	}
+	if _, err := q.Put(5); err != queue.ErrQueueIsClosed {
+		t.Fatal("err != ErrQueueIsClosed")
+	}
+
+	q = queue.New[int](8)
+	q.PutEnough(1, 2, 3, 4)
+	ctx, cancel := context.WithCancel(context.Background())
+	left := q.CloseAndDrain(ctx, func(v int) {
+		if v == 2 {
+			cancel()
+		}
+	})
+	if left != 2 {
+		t.Fatalf("left %d != 2", left)
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	q := queue.New[int](8)
+	q.PutEnough(1, 2)
+	q.Pause()
+	if !q.IsPaused() {
+		t.Fatal("not paused")
+	}
+	if _, err := q.Put(3); err != queue.ErrQueuePaused {
+		t.Fatal("err != ErrQueuePaused")
+	}
+	if _, err := q.PutContext(context.Background(), 3); err != queue.ErrQueuePaused {
+		t.Fatal("err != ErrQueuePaused")
+	}
+	if val, _, err := q.Get(); err != nil || val != 1 {
+		t.Fatal("Get blocked by Pause")
+	}
+	q.Resume()
+	if _, err := q.Put(3); err != nil {
+		t.Fatal(err)
+	}
+
+	q = queue.New[int](8, queue.WithBlockWhenPaused())
+	q.Pause()
+	done := make(chan error)
+	go func() {
+		_, err := q.PutContext(context.Background(), 1)
+		done <- err
+	}()
+	select {
+	case <-done:
+		t.Fatal("PutContext not blocked while paused")
+	case <-time.After(time.Millisecond * 20):
+	}
+	q.Resume()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("PutContext not woken by Resume")
+	}
+	if q.Len() != 1 {
+		t.Fatal("Len != 1")
+	}
+}
+
+func TestWithFullPolicy(t *testing.T) {
+	var dropped []int
+	onDrop := func(v int) { dropped = append(dropped, v) }
+
+	q := queue.New[int](2, queue.WithFullPolicy(queue.FullDropNewest, onDrop))
+	q.PutEnough(1, 2)
+	if _, err := q.Put(3); err != nil {
+		t.Fatal(err)
+	}
+	if len(dropped) != 1 || dropped[0] != 3 {
+		t.Fatal("dropped != [3]")
+	}
+	if val, _, _ := q.Get(); val != 1 {
+		t.Fatal("val != 1")
+	}
+
+	dropped = nil
+	q = queue.New[int](2, queue.WithFullPolicy(queue.FullDropOldest, onDrop))
+	q.PutEnough(1, 2)
+	if _, err := q.Put(3); err != nil {
+		t.Fatal(err)
+	}
+	if len(dropped) != 1 || dropped[0] != 1 {
+		t.Fatal("dropped != [1]")
+	}
+	if val, _, _ := q.Get(); val != 2 {
+		t.Fatal("val != 2")
+	}
+
+	q = queue.New[int](2, queue.WithFullPolicy[int](queue.FullBlock, nil))
+	q.PutEnough(1, 2)
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		q.MustGet()
+	}()
+	if _, err := q.Put(3); err != nil {
+		t.Fatal(err)
+	}
+
+	q = queue.New[int](2, queue.WithFullPolicy[int](queue.FullReject, nil))
+	q.PutEnough(1, 2)
+	if _, err := q.Put(3); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+}
+
+func TestWithExactCapacity(t *testing.T) {
+	q := queue.New[int](3, queue.WithExactCapacity())
+	if q.Cap() != 3 {
+		t.Fatal("cap != 3")
+	}
+	stored, left := q.PutEnough(1, 2, 3, 4)
+	if left != 0 || stored != 3 || !q.IsFull() {
+		t.Fatal("stored != 3")
+	}
+	if _, err := q.Put(5); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	for i := 0; i < 10; i++ {
+		if val, _, err := q.Get(); err != nil || val != i+1 {
+			t.Fatal("val mismatch")
+		}
+		if left, err := q.Put(i + 4); err != nil || left != 0 {
+			t.Fatal("left != 0")
+		}
+	}
+	if _, err := q.GetExactly(context.Background(), 4); err != queue.ErrNotEnough {
+		t.Fatal("err != ErrNotEnough")
+	}
+
+	if q = queue.New[int](0, queue.WithExactCapacity()); q.Cap() != 2 {
+		t.Fatal("cap != 2")
+	}
+}
+
+func TestResize(t *testing.T) {
+	if err := queue.New[int](4).Resize(8); err != queue.ErrNotResizable {
+		t.Fatal("err != ErrNotResizable")
+	}
+
+	q := queue.New[int](4, queue.WithResizable())
+	q.Get()
+	q.PutEnough(1, 2, 3, 4)
+	if err := q.Resize(2); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	if err := q.Resize(8); err != nil || q.Cap() != 8 {
+		t.Fatal("cap != 8")
+	}
+	if n, _ := q.PutEnough(5, 6, 7, 8, 9); n != 4 {
+		t.Fatal("n != 4")
+	}
+	for i := 1; i <= 6; i++ {
+		if val, _, _ := q.Get(); val != i {
+			t.Fatal("val mismatch")
+		}
+	}
+	if err := q.Resize(2); err != nil || q.Cap() != 2 || q.Len() != 2 {
+		t.Fatal("cap != 2")
+	}
+	if val, _, _ := q.GetEnough(2); val[0] != 7 || val[1] != 8 {
+		t.Fatal("val mismatch")
+	}
+
+	q = queue.New[int](4, queue.WithResizable())
+	const count = 20000
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= count; i++ {
+			q.MustPut(i)
+		}
+	}()
+	sum := 0
+	go func() {
+		defer wg.Done()
+		for i := 0; i < count; i++ {
+			val, _ := q.MustGet()
+			sum += val
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		_ = q.Resize(uint32(4 << (i % 4)))
+		time.Sleep(time.Microsecond * 100)
+	}
+	wg.Wait()
+	if sum != count*(count+1)/2 {
+		t.Fatalf("sum %d mismatch", sum)
+	}
+}
+
+func TestWithAutoGrow(t *testing.T) {
+	q := queue.New[int](2, queue.WithAutoGrow(8, time.Millisecond*10))
+	for i := 0; i < 8; i++ {
+		if _, err := q.Put(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if q.Cap() != 8 {
+		t.Fatal("cap != 8")
+	}
+	if _, err := q.Put(8); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	for i := 0; i < 8; i++ {
+		if val, _, _ := q.Get(); val != i {
+			t.Fatal("val mismatch")
+		}
+	}
+
+	q.Put(1)
+	q.Get()
+	time.Sleep(time.Millisecond * 20)
+	q.Put(1)
+	q.Get()
+	if q.Cap() != 4 {
+		t.Fatal("cap != 4")
+	}
+	for i := 0; i < 3; i++ {
+		time.Sleep(time.Millisecond * 20)
+		q.Put(1)
+		q.Get()
+	}
+	if q.Cap() != 2 {
+		t.Fatal("cap != 2")
+	}
+
+	q = queue.New[int](3, queue.WithExactCapacity(), queue.WithAutoGrow(10, 0))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := 0; i < 10; i++ {
+		if _, err := q.PutContext(ctx, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if q.Cap() != 10 {
+		t.Fatal("cap != 10")
+	}
+}
+
+func TestWithSegmentSize(t *testing.T) {
+	q := queue.New[int](64, queue.WithSegmentSize(8), queue.WithResizable())
+	if q.Cap() != 64 {
+		t.Fatal("cap != 64")
+	}
+	next := 0
+	for round := 0; round < 5; round++ {
+		values := make([]int, 50)
+		for i := range values {
+			values[i] = round*50 + i
+		}
+		if n, _ := q.PutEnough(values...); n != 50 {
+			t.Fatal("n != 50")
+		}
+		for i := 0; i < 50; i++ {
+			if val, _, _ := q.Get(); val != next {
+				t.Fatal("val mismatch")
+			}
+			next++
+		}
+	}
+	q.PutEnough(1, 2, 3)
+	if err := q.Resize(128); err != nil {
+		t.Fatal(err)
+	}
+	if len(q.DumpState(false).Slots) != 128 {
+		t.Fatal("slots != 128")
+	}
+	if val, _, _ := q.GetEnough(3); val[0] != 1 || val[2] != 3 {
+		t.Fatal("val mismatch")
+	}
+
+	q = queue.New[int](4, queue.WithSegmentSize(8))
+	q.PutEnough(1, 2, 3, 4)
+	if val, _, _ := q.GetEnough(4); val[3] != 4 {
+		t.Fatal("val mismatch")
+	}
+}
+
+func TestClear(t *testing.T) {
+	q := queue.New[int](4, queue.WithWeights(func(v int) uint32 { return uint32(v) }, 10))
+	if q.Clear() != 0 {
+		t.Fatal("cleared != 0")
+	}
+	q.PutEnough(1, 2, 3)
+	if q.Clear() != 3 || !q.IsEmpty() {
+		t.Fatal("cleared != 3")
+	}
+	if q.Weight() != 0 {
+		t.Fatal("weight != 0")
+	}
+	if n, _ := q.PutEnough(4, 5); n != 2 {
+		t.Fatal("n != 2")
+	}
+	if val, _, _ := q.Get(); val != 4 {
+		t.Fatal("val != 4")
+	}
+}
+
+func TestDrain(t *testing.T) {
+	q := queue.New[int](128)
+	for i := 0; i < 100; i++ {
+		q.Put(i)
+	}
+	next := 0
+	n := q.Drain(func(v int) {
+		if v != next {
+			t.Fatal("val mismatch")
+		}
+		next++
+		if v == 10 {
+			q.Put(100)
+		}
+	})
+	if n != 101 || !q.IsEmpty() {
+		t.Fatal("n != 101")
+	}
+
+	for i := 0; i < 100; i++ {
+		q.Put(i)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	n, err := q.DrainContext(ctx, func(v int) {
+		if v == 0 {
+			cancel()
+		}
+	})
+	if err != context.Canceled || n != 64 || q.Len() != 36 {
+		t.Fatal("err != Canceled")
+	}
+	if n, err = q.DrainContext(context.Background(), func(int) {}); err != nil || n != 36 {
+		t.Fatal("n != 36")
+	}
+}
+
+func TestGetInto(t *testing.T) {
+	q := queue.New[int](8)
+	buf := make([]int, 3)
+	if n, _ := q.GetInto(buf); n != 0 {
+		t.Fatal("n != 0")
+	}
+	q.PutEnough(1, 2, 3, 4, 5)
+	if n, used := q.GetInto(buf); n != 3 || used != 2 || buf[0] != 1 || buf[2] != 3 {
+		t.Fatal("buf != [1 2 3]")
+	}
+	if n, used := q.GetInto(buf); n != 2 || used != 0 || buf[0] != 4 || buf[1] != 5 {
+		t.Fatal("buf != [4 5]")
+	}
+	if allocs := testing.AllocsPerRun(100, func() {
+		q.PutEnough(1, 2, 3)
+		q.GetInto(buf)
+	}); allocs > 0 {
+		t.Fatal("allocs > 0")
+	}
+}
+
+func TestGetEnoughFunc(t *testing.T) {
+	q := queue.New[int](8)
+	sum := 0
+	if n, _ := q.GetEnoughFunc(3, func(v int) { sum += v }); n != 0 {
+		t.Fatal("n != 0")
+	}
+	q.PutEnough(1, 2, 3, 4, 5)
+	next := 1
+	n, used := q.GetEnoughFunc(3, func(v int) {
+		if v != next {
+			t.Fatal("val mismatch")
+		}
+		next++
+		sum += v
+	})
+	if n != 3 || used != 2 || sum != 6 {
+		t.Fatal("sum != 6")
+	}
+	if n, used = q.GetEnoughFunc(8, func(v int) { sum += v }); n != 2 || used != 0 || sum != 15 {
+		t.Fatal("sum != 15")
+	}
+}
+
+func TestRemoveWhere(t *testing.T) {
+	q := queue.New[int](8, queue.WithResizable())
+	q.Get()
+	q.PutEnough(1, 2, 3, 4, 5, 6)
+	q.GetEnough(1)
+	if n, err := q.RemoveWhere(func(v int) bool { return v%2 == 0 }); err != nil || n != 3 {
+		t.Fatal("n != 3")
+	}
+	if q.Len() != 2 {
+		t.Fatal("len != 2")
+	}
+	if values, _, _ := q.GetEnough(2); values[0] != 3 || values[1] != 5 {
+		t.Fatal("values != [3 5]")
+	}
+	if n, _ := q.RemoveWhere(func(int) bool { return true }); n != 0 {
+		t.Fatal("n != 0")
+	}
+
+	q = queue.New[int](4, queue.WithResizable(), queue.WithWeights(func(v int) uint32 { return uint32(v) }, 10))
+	q.PutEnough(4, 5)
+	q.RemoveWhere(func(v int) bool { return v == 5 })
+	if q.Weight() != 4 {
+		t.Fatal("weight != 4")
+	}
+
+	if _, err := queue.New[int](4).RemoveWhere(func(int) bool { return true }); err != queue.ErrNotResizable {
+		t.Fatal("err != ErrNotResizable")
+	}
+
+	const count = 20000
+	q = queue.New[int](64, queue.WithResizable())
+	var consumed, removed uint32
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < count; i++ {
+			q.MustPut(i)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		last := -1
+		for atomic.LoadUint32(&consumed)+atomic.LoadUint32(&removed) < count {
+			val, _, err := q.Get()
+			if err != nil {
+				runtime.Gosched()
+				continue
+			}
+			if val <= last {
+				t.Errorf("val %d after %d", val, last)
+				return
+			}
+			last = val
+			atomic.AddUint32(&consumed, 1)
+		}
+	}()
+	for atomic.LoadUint32(&consumed)+atomic.LoadUint32(&removed) < count {
+		n, _ := q.RemoveWhere(func(v int) bool { return v%3 == 0 })
+		atomic.AddUint32(&removed, n)
+		runtime.Gosched()
+	}
+	wg.Wait()
+}
+
+func TestWithNameAndZeroing(t *testing.T) {
+	q := queue.New[int](2, queue.WithName("jobs"))
+	q.Put(1)
+	if q.Name() != "jobs" || q.String() != "Queue(jobs): Head:0 Tail:1 Len:1 Cap:2" {
+		t.Fatal(q.String())
+	}
+	q.Get()
+	if q.DumpState(true).Slots[1].Value != 0 {
+		t.Fatal("slot not zeroed")
+	}
+
+	q = queue.New[int](2, queue.WithoutZeroing())
+	q.Put(1)
+	q.Get()
+	if q.DumpState(true).Slots[1].Value != 1 {
+		t.Fatal("slot zeroed")
+	}
+}
+
+func TestWithPadding(t *testing.T) {
+	for _, q := range []*queue.Queue[int]{
+		queue.New[int](8, queue.WithPadding(queue.PadNone)),
+		queue.New[int](8, queue.WithPadding(queue.PadSlots)),
+		queue.New[int](16, queue.WithPadding(queue.PadSlots), queue.WithSegmentSize(4)),
+	} {
+		next := 0
+		for round := 0; round < 5; round++ {
+			for i := 0; i < 6; i++ {
+				if _, err := q.Put(round*6 + i); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i := 0; i < 6; i++ {
+				if val, _, _ := q.Get(); val != next {
+					t.Fatal("val mismatch")
+				}
+				next++
+			}
+		}
+		if len(q.DumpState(false).Slots) != int(q.Cap()) {
+			t.Fatal("slots != cap")
+		}
+	}
+}
+
+func TestNewE(t *testing.T) {
+	opts := []queue.Option{
+		queue.WithWeights(func(s string) uint32 { return 1 }, 4),
+		queue.WithFullPolicy(queue.FullDropOldest, func(string) {}),
+		queue.WithElementCodec[string](queue.JSONCodec[string]{}),
+	}
+	for _, opt := range opts {
+		if q, err := queue.NewE[int](4, opt); !errors.Is(err, queue.ErrOptionType) || q != nil {
+			t.Fatal("err != ErrOptionType")
+		}
+		if _, err := queue.NewE[string](4, opt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := queue.NewE[int](4, queue.WithFullPolicy[string](queue.FullDropOldest, nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if p, _ := recover().(error); !errors.Is(p, queue.ErrOptionType) {
+			t.Fatal("New not panic")
+		}
+	}()
+	queue.New[int](4, opts[0])
+}
+
+func TestWithDetailedErrors(t *testing.T) {
+	q := queue.New[int](2, queue.WithName("jobs"), queue.WithDetailedErrors())
+	if _, _, err := q.Get(); !errors.Is(err, queue.ErrQueueIsEmpty) || err.Error() != "jobs: 队列为空" {
+		t.Fatal(err)
+	}
+	q.PutEnough(1, 2)
+	_, err := q.Put(3)
+	var fullErr *queue.FullError
+	if !errors.Is(err, queue.ErrQueueIsFull) || !errors.As(err, &fullErr) || fullErr.Capacity != 2 || fullErr.Name != "jobs" {
+		t.Fatal(err)
+	}
+	if err.Error() != "jobs: 队列已满，容量2，请求1" || fullErr.Requested != 1 {
+		t.Fatal(err)
+	}
+	q.Get()
+	if _, _, err = q.PutEnoughE(3, 4, 5); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = q.PutEnoughE(6, 7, 8); !errors.As(err, &fullErr) || fullErr.Requested != 3 {
+		t.Fatal("requested != 3")
+	}
+	if err = q.PutAtomic(6, 7); !errors.As(err, &fullErr) || fullErr.Requested != 2 {
+		t.Fatal("requested != 2")
+	}
+	if _, _, err = q.ReserveSlot(); !errors.As(err, &fullErr) || fullErr.Requested != 1 {
+		t.Fatal("requested != 1")
+	}
+	if err = q.WaitForSpace(context.Background(), 5); !errors.As(err, &fullErr) || fullErr.Requested != 5 {
+		t.Fatal("requested != 5")
+	}
+	if _, err = q.PutTimeout(3, time.Millisecond); !errors.Is(err, queue.ErrQueueIsFull) {
+		t.Fatal(err)
+	}
+}
+
+func TestSetErrorLocale(t *testing.T) {
+	defer queue.SetErrorLocale(queue.LocaleChinese)
+	q := queue.New[int](2, queue.WithName("jobs"), queue.WithDetailedErrors())
+	q.PutEnough(1, 2)
+	_, fullErr := q.Put(3)
+	queue.SetErrorLocale(queue.LocaleEnglish)
+	if queue.ErrQueueIsEmpty.Error() != "queue is empty" || fullErr.Error() != "jobs: queue is full, capacity 2, requested 1" {
+		t.Fatal("message not english")
+	}
+	if !errors.Is(fullErr, queue.ErrQueueIsFull) {
+		t.Fatal("err is not ErrQueueIsFull")
+	}
+	queue.SetErrorLocale(queue.LocaleChinese)
+	if queue.ErrQueueIsEmpty.Error() != "队列为空" {
+		t.Fatal("message not chinese")
+	}
+}
+
+func TestPutEnoughE(t *testing.T) {
+	q := queue.New[int](4)
+	if accepted, left, err := q.PutEnoughE(); accepted != 0 || left != 4 || err != nil {
+		t.Fatal("empty input mismatch")
+	}
+	if accepted, left, err := q.PutEnoughE(1, 2, 3); accepted != 3 || left != 1 || err != nil {
+		t.Fatal("accepted != 3")
+	}
+	if accepted, left, err := q.PutEnoughE(4, 5); accepted != 1 || left != 0 || err != nil {
+		t.Fatal("accepted != 1")
+	}
+	if accepted, _, err := q.PutEnoughE(6); accepted != 0 || err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	q.Close()
+	q.Get()
+	if _, _, err := q.PutEnoughE(6); err != queue.ErrQueueIsClosed {
+		t.Fatal("err != ErrQueueIsClosed")
+	}
+
+	q = queue.New[int](8, queue.WithWeights(func(v int) uint32 { return uint32(v) }, 5))
+	if accepted, _, err := q.PutEnoughE(3, 3); accepted != 1 || err != nil {
+		t.Fatal("accepted != 1")
+	}
+	if _, _, err := q.PutEnoughE(3); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+}
+
+func TestGetEnoughE(t *testing.T) {
+	q := queue.New[int](4)
+	if values, taken, remaining, err := q.GetEnoughE(2); values != nil || taken != 0 || remaining != 0 || err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+	q.PutEnough(1, 2, 3)
+	if values, taken, remaining, err := q.GetEnoughE(0); len(values) != 0 || taken != 0 || remaining != 3 || err != nil {
+		t.Fatal("remaining != 3")
+	}
+	values, taken, remaining, err := q.GetEnoughE(2)
+	if err != nil || taken != 2 || remaining != 1 || values[0] != 1 || values[1] != 2 {
+		t.Fatal("values != [1 2]")
+	}
+	if values, taken, remaining, err = q.GetEnoughE(5); err != nil || taken != 1 || remaining != 0 || values[0] != 3 {
+		t.Fatal("values != [3]")
+	}
+	q.Close()
+	if _, _, _, err = q.GetEnoughE(1); err != queue.ErrQueueIsClosed {
+		t.Fatal("err != ErrQueueIsClosed")
+	}
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// 交接槽状态。
+const (
+	rendezvousEmpty uint32 = iota
+	rendezvousWriting
+	rendezvousOffered
+	rendezvousTaking
+	rendezvousTaken
+)
+
+// Rendezvous 无缓冲的同步交接队列。Put 等待直至数据被 Get 取走，Get 等待直至有数据交出，类似无缓冲通道。
+//
+// 交接过程不分配内存，仅在等待时长超出自旋阶段、需挂起协程时创建唤醒通道。
+type Rendezvous[E any] struct {
+	state   uint32
+	value   E
+	changed notifier
+}
+
+// NewRendezvous 创建同步交接队列。
+func NewRendezvous[E any]() *Rendezvous[E] {
+	return &Rendezvous[E]{}
+}
+
+// Put 交出数据，等待直至被 Get 取走。ctx 结束前未被取走时返回 ctx.Err()，此时数据不会被取出。
+func (r *Rendezvous[E]) Put(ctx context.Context, value E) error {
+	err := await(ctx, &r.changed, func() bool { return atomic.CompareAndSwapUint32(&r.state, rendezvousEmpty, rendezvousWriting) })
+	if err != nil {
+		return err
+	}
+	r.value = value
+	atomic.StoreUint32(&r.state, rendezvousOffered)
+	r.changed.notify()
+
+	if err = await(ctx, &r.changed, func() bool { return atomic.LoadUint32(&r.state) == rendezvousTaken }); err != nil {
+		if atomic.CompareAndSwapUint32(&r.state, rendezvousOffered, rendezvousWriting) {
+			var empty E
+			r.value = empty
+			atomic.StoreUint32(&r.state, rendezvousEmpty)
+			r.changed.notify()
+			return err
+		}
+		// 数据已被认领，等待取出完成。
+		_ = await(context.Background(), &r.changed, func() bool { return atomic.LoadUint32(&r.state) == rendezvousTaken })
+	}
+	atomic.StoreUint32(&r.state, rendezvousEmpty)
+	r.changed.notify()
+	return nil
+}
+
+// Get 取出数据，等待直至有 Put 交出数据。ctx 结束前未取到数据时返回 ctx.Err()。
+func (r *Rendezvous[E]) Get(ctx context.Context) (E, error) {
+	err := await(ctx, &r.changed, func() bool { return atomic.CompareAndSwapUint32(&r.state, rendezvousOffered, rendezvousTaking) })
+	if err != nil {
+		var empty E
+		return empty, err
+	}
+	val := r.value
+	var empty E
+	r.value = empty
+	atomic.StoreUint32(&r.state, rendezvousTaken)
+	r.changed.notify()
+	return val, nil
+}
+
+// TryGet 若有 Put 正在等待则取出其数据，否则立即返回 ErrQueueIsEmpty。
+func (r *Rendezvous[E]) TryGet() (E, error) {
+	if !atomic.CompareAndSwapUint32(&r.state, rendezvousOffered, rendezvousTaking) {
+		var empty E
+		return empty, ErrQueueIsEmpty
+	}
+	val := r.value
+	var empty E
+	r.value = empty
+	atomic.StoreUint32(&r.state, rendezvousTaken)
+	r.changed.notify()
+	return val, nil
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestRendezvous(t *testing.T) {
+	r := queue.NewRendezvous[int]()
+	if _, err := r.TryGet(); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+
+	var handed int32
+	done := make(chan error)
+	go func() {
+		err := r.Put(context.Background(), 1)
+		atomic.StoreInt32(&handed, 1)
+		done <- err
+	}()
+	time.Sleep(time.Millisecond * 20)
+	if atomic.LoadInt32(&handed) != 0 {
+		t.Fatal("Put returned before Get")
+	}
+	val, err := r.Get(context.Background())
+	if err != nil || val != 1 {
+		t.Fatal("val != 1")
+	}
+	if err = <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	if err = r.Put(ctx, 2); err != context.DeadlineExceeded {
+		t.Fatal("err != DeadlineExceeded")
+	}
+	if _, err = r.TryGet(); err != queue.ErrQueueIsEmpty {
+		t.Fatal("withdrawn value taken")
+	}
+}
+
+func TestRendezvousConcurrent(t *testing.T) {
+	const (
+		producers = 8
+		count     = 500
+	)
+	r := queue.NewRendezvous[int]()
+	wg := sync.WaitGroup{}
+	for i := 0; i < producers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 1; j <= count; j++ {
+				if err := r.Put(context.Background(), j); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	var sum int64
+	for i := 0; i < producers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < count; j++ {
+				val, err := r.Get(context.Background())
+				if err != nil {
+					t.Error(err)
+				}
+				atomic.AddInt64(&sum, int64(val))
+			}
+		}()
+	}
+	wg.Wait()
+	if sum != producers*count*(count+1)/2 {
+		t.Fatalf("sum %d mismatch", sum)
+	}
+}
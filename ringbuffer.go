@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+// RingBuffer 环形缓冲区。已满时填充数据将覆盖最旧的未读数据，而不是返回错误，适合保留最近 N 条记录。使用 NewRingBuffer 创建变量。
+type RingBuffer[E any] struct {
+	q       *Queue[E]
+	onEvict func(E)
+}
+
+// NewRingBuffer 创建环形缓冲区。capacity 缓冲区长度，调整规则同 New。onEvict 数据被覆盖时回调，可为 nil。opts 同 New。
+func NewRingBuffer[E any](capacity uint32, onEvict func(E), opts ...Option) *RingBuffer[E] {
+	return &RingBuffer[E]{q: New[E](capacity, opts...), onEvict: onEvict}
+}
+
+// Put 向缓冲区尾部填充数据，已满时覆盖最旧的数据。返回剩余可填充数据个数。
+//
+// 多个协程同时填充时，可能连续覆盖多个数据，每个被覆盖的数据都会回调 onEvict。
+// 使用 WithWeights 创建且数据权重超过上限时返回 ErrQueueIsFull，此时数据未被填充，也不覆盖任何数据。
+func (b *RingBuffer[E]) Put(value E) (uint32, error) {
+	left, err := b.q.putEvicting(value, b.onEvict)
+	return left, b.q.fullError(err, 1)
+}
+
+// PutEnough 向缓冲区依次填充多个数据，已满时覆盖最旧的数据。返回实际填充数据个数，剩余可填充数据个数。
+// 某个数据填充失败时停止，返回其错误，错误同 Put。
+func (b *RingBuffer[E]) PutEnough(values ...E) (uint32, uint32, error) {
+	var n, left uint32
+	for _, v := range values {
+		var err error
+		if left, err = b.Put(v); err != nil {
+			return n, left, err
+		}
+		n++
+	}
+	return n, left, nil
+}
+
+// Get 取出最旧的数据。返回数据，剩余可取个数。无数据可取时返回错误 ErrQueueIsEmpty。
+func (b *RingBuffer[E]) Get() (E, uint32, error) {
+	return b.q.Get()
+}
+
+// GetEnough 取出多个数据。返回数据，实际取出数据个数，剩余可取数据个数。
+func (b *RingBuffer[E]) GetEnough(size uint32) ([]E, uint32, uint32) {
+	return b.q.GetEnough(size)
+}
+
+// DrainEach 取出缓冲区当前所有数据，并按先进先出顺序逐个调用 fn。返回取出数据个数。
+func (b *RingBuffer[E]) DrainEach(fn func(E)) uint32 {
+	return b.q.DrainEach(fn)
+}
+
+// Cap 返回缓冲区长度。
+func (b *RingBuffer[E]) Cap() uint32 {
+	return b.q.Cap()
+}
+
+// Len 返回缓冲区数据个数。
+func (b *RingBuffer[E]) Len() uint32 {
+	return b.q.Len()
+}
+
+// IsEmpty 判断缓冲区是否有数据。
+func (b *RingBuffer[E]) IsEmpty() bool {
+	return b.q.IsEmpty()
+}
+
+// IsFull 判断缓冲区是否已满。
+func (b *RingBuffer[E]) IsFull() bool {
+	return b.q.IsFull()
+}
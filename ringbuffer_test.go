@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestRingBuffer(t *testing.T) {
+	var evicted []int
+	b := queue.NewRingBuffer[int](4, func(v int) { evicted = append(evicted, v) })
+	for i := 1; i <= 10; i++ {
+		b.Put(i)
+	}
+	if !b.IsFull() || b.Len() != 4 {
+		t.Fatal("Len != 4")
+	}
+	if len(evicted) != 6 || evicted[0] != 1 || evicted[5] != 6 {
+		t.Fatal("evicted != [1..6]")
+	}
+	vals, _, _ := b.GetEnough(4)
+	for i, v := range vals {
+		if v != i+7 {
+			t.Fatal("vals != [7 8 9 10]")
+		}
+	}
+
+	b = queue.NewRingBuffer[int](2, nil)
+	if n, left, err := b.PutEnough(1, 2, 3); n != 3 || left != 0 || err != nil {
+		t.Fatal("PutEnough mismatch")
+	}
+	if val, _, _ := b.Get(); val != 2 {
+		t.Fatal("val != 2")
+	}
+
+	evicted = nil
+	b = queue.NewRingBuffer[int](4, func(v int) { evicted = append(evicted, v) },
+		queue.WithWeights(func(v int) uint32 { return uint32(v) }, 5))
+	if _, err := b.Put(2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Put(6); err != queue.ErrQueueIsFull || len(evicted) != 0 || b.Len() != 1 {
+		t.Fatal("oversized value not rejected")
+	}
+	if n, _, err := b.PutEnough(1, 9, 1); n != 1 || err != queue.ErrQueueIsFull || b.Len() != 2 {
+		t.Fatal("PutEnough not stopped")
+	}
+}
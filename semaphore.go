@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Semaphore 计数信号量。许可以令牌形式保存在队列中，获取许可即取出令牌。使用 NewSemaphore 创建变量。
+type Semaphore struct {
+	q        *Queue[struct{}]
+	permits  uint32
+	acquired int64
+}
+
+// RateLimiter 令牌桶限流器。令牌保存在队列中，按固定间隔惰性补充。使用 NewRateLimiter 创建变量。
+type RateLimiter struct {
+	q     *Queue[struct{}]
+	every int64
+	burst uint32
+	last  int64
+}
+
+// NewSemaphore 创建信号量。permits 许可个数，不可为0。
+func NewSemaphore(permits uint32) *Semaphore {
+	if permits == 0 {
+		panic("permits is zero")
+	}
+	s := &Semaphore{q: New[struct{}](permits), permits: permits}
+	for i := uint32(0); i < permits; i++ {
+		_, _ = s.q.tryPut(struct{}{})
+	}
+	return s
+}
+
+// Acquire 获取一个许可，无许可时阻塞直到有许可释放或 ctx 结束。
+func (s *Semaphore) Acquire(ctx context.Context) error {
+	if _, _, err := s.q.GetContext(ctx); err != nil {
+		return err
+	}
+	atomic.AddInt64(&s.acquired, 1)
+	return nil
+}
+
+// TryAcquire 尝试获取一个许可，无许可时返回 false。
+func (s *Semaphore) TryAcquire() bool {
+	if _, _, err := s.q.Get(); err != nil {
+		return false
+	}
+	atomic.AddInt64(&s.acquired, 1)
+	return true
+}
+
+// Release 释放一个许可。释放次数多于获取次数时将 panic。
+func (s *Semaphore) Release() {
+	if atomic.AddInt64(&s.acquired, -1) < 0 {
+		atomic.AddInt64(&s.acquired, 1)
+		panic("semaphore released more than acquired")
+	}
+	_, _ = s.q.tryPut(struct{}{})
+}
+
+// Available 返回当前可获取许可个数。
+func (s *Semaphore) Available() uint32 {
+	return s.q.Len()
+}
+
+// Permits 返回许可总数。
+func (s *Semaphore) Permits() uint32 {
+	return s.permits
+}
+
+// NewRateLimiter 创建限流器。every 每补充一个令牌的间隔，burst 桶容量，不可为0。创建时桶是满的。
+func NewRateLimiter(every time.Duration, burst uint32) *RateLimiter {
+	if every <= 0 {
+		panic("every must be positive")
+	}
+	if burst == 0 {
+		panic("burst is zero")
+	}
+	l := &RateLimiter{q: New[struct{}](burst), every: int64(every), burst: burst, last: time.Now().UnixNano()}
+	for i := uint32(0); i < burst; i++ {
+		_, _ = l.q.tryPut(struct{}{})
+	}
+	return l
+}
+
+// Allow 尝试取出一个令牌，无令牌时返回 false。
+func (l *RateLimiter) Allow() bool {
+	l.refill()
+	_, _, err := l.q.Get()
+	return err == nil
+}
+
+// Wait 取出一个令牌，无令牌时阻塞直到补充令牌或 ctx 结束。
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		if l.Allow() {
+			return nil
+		}
+		next := time.Duration(atomic.LoadInt64(&l.last) + l.every - time.Now().UnixNano())
+		if next <= 0 {
+			continue
+		}
+		timer := time.NewTimer(next)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Tokens 返回桶中当前令牌个数。
+func (l *RateLimiter) Tokens() uint32 {
+	l.refill()
+	return l.q.Len()
+}
+
+// 按流逝时间补充令牌，桶满时多余令牌丢弃。
+func (l *RateLimiter) refill() {
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&l.last)
+	n := (now - last) / l.every
+	if n <= 0 || !atomic.CompareAndSwapInt64(&l.last, last, last+n*l.every) {
+		return
+	}
+	for ; n > 0 && l.q.Len() < l.burst; n-- {
+		if _, err := l.q.tryPut(struct{}{}); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestSemaphore(t *testing.T) {
+	s := queue.NewSemaphore(3)
+	for i := 0; i < 3; i++ {
+		if !s.TryAcquire() {
+			t.Fatal("acquire failed")
+		}
+	}
+	if s.TryAcquire() {
+		t.Fatal("acquired beyond permits")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if err := s.Acquire(ctx); err != context.DeadlineExceeded {
+		t.Fatal("err != DeadlineExceeded")
+	}
+	s.Release()
+	if err := s.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		s.Release()
+	}
+	if s.Available() != 3 {
+		t.Fatal("available != 3")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("over release not panic")
+			}
+		}()
+		s.Release()
+	}()
+
+	var running, maxRunning int32
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = s.Acquire(context.Background())
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			s.Release()
+		}()
+	}
+	wg.Wait()
+	if maxRunning > 3 {
+		t.Fatal("maxRunning > 3")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := queue.NewRateLimiter(time.Millisecond*20, 2)
+	if !l.Allow() || !l.Allow() {
+		t.Fatal("burst not allowed")
+	}
+	if l.Allow() {
+		t.Fatal("allowed beyond burst")
+	}
+	start := time.Now()
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < time.Millisecond*10 {
+		t.Fatal("wait returned too early")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatal("err != DeadlineExceeded")
+	}
+	time.Sleep(time.Millisecond * 100)
+	if l.Tokens() != 2 {
+		t.Fatal("tokens != 2")
+	}
+}
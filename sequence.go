@@ -0,0 +1,56 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import "sync/atomic"
+
+// PutSequenced 向队列尾部填充数据。返回数据序号，剩余可填充数据个数。错误同 Put，但不使用 WithFullPolicy 设置的策略。
+//
+// 序号从1开始，每填充一个数据加一，Compact 和 Resize 不影响序号。序号按 uint32 回绕，比较先后时应使用差值。
+func (q *Queue[E]) PutSequenced(value E) (uint32, uint32, error) {
+	seq, left, err := q.tryPutSequenced(value)
+	return seq, left, q.fullError(err, 1)
+}
+
+// GetSequenced 取出队列头部数据。返回数据，数据序号，剩余可取个数。错误同 Get。
+func (q *Queue[E]) GetSequenced() (E, uint32, uint32, error) {
+	var val E
+	position, _, used, err := q.acquireGet(1, 1)
+	if err != nil {
+		return val, 0, 0, err
+	}
+	val = q.get(position)
+	seq := q.sequenceOf(position)
+	q.leave()
+	q.maybeShrink(used)
+	return val, seq, used, nil
+}
+
+// ProducerSequence 返回最近一次被认领的填充位置的序号，即已填充数据总数，包含正在写入的数据。
+func (q *Queue[E]) ProducerSequence() uint32 {
+	q.enter()
+	defer q.leave()
+	return atomic.LoadUint32(&q.seqOffset) + atomic.LoadUint32(&q.tail)
+}
+
+// ConsumerSequence 返回最近一次被认领的取出位置的序号，即已取出数据总数，包含正在读取的数据。
+func (q *Queue[E]) ConsumerSequence() uint32 {
+	q.enter()
+	defer q.leave()
+	return atomic.LoadUint32(&q.seqOffset) + atomic.LoadUint32(&q.head)
+}
+
+// 返回位置对应的序号。调用方须处于槽位访问区间。
+func (q *Queue[E]) sequenceOf(position uint32) uint32 {
+	return atomic.LoadUint32(&q.seqOffset) + position
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestSequence(t *testing.T) {
+	q := queue.New[int](4, queue.WithResizable())
+	for i := 1; i <= 3; i++ {
+		if seq, _, err := q.PutSequenced(i * 10); err != nil || seq != uint32(i) {
+			t.Fatal("seq mismatch")
+		}
+	}
+	if val, seq, used, err := q.GetSequenced(); err != nil || val != 10 || seq != 1 || used != 2 {
+		t.Fatal("seq != 1")
+	}
+	if q.ProducerSequence() != 3 || q.ConsumerSequence() != 1 {
+		t.Fatal("sequence mismatch")
+	}
+
+	q.Compact()
+	if q.ProducerSequence() != 3 || q.ConsumerSequence() != 1 {
+		t.Fatal("sequence changed by Compact")
+	}
+	if err := q.Resize(8); err != nil {
+		t.Fatal(err)
+	}
+	if seq, _, _ := q.PutSequenced(40); seq != 4 {
+		t.Fatal("seq != 4")
+	}
+	for i := 2; i <= 4; i++ {
+		if val, seq, _, _ := q.GetSequenced(); val != i*10 || seq != uint32(i) {
+			t.Fatal("seq mismatch")
+		}
+	}
+	if q.ConsumerSequence() != 4 {
+		t.Fatal("consumer sequence != 4")
+	}
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import "sync/atomic"
+
+// ShardedQueue 分片队列。数据分散到多个内部队列，以降低大量生产者竞争同一尾部位置时的 CAS 重试。使用 NewSharded 创建变量。
+//
+// 只保证同一分片内先进先出，不保证全局先进先出：先填充的数据可能晚于后填充的数据被取出。
+// 同一协程连续填充的数据也可能落在不同分片。需要严格顺序时应使用 Queue。
+type ShardedQueue[E any] struct {
+	putNext uint32
+	_       [cacheLinePadSize - 4]byte
+	getNext uint32
+	_       [cacheLinePadSize - 4]byte
+	shards  []*Queue[E]
+}
+
+// NewSharded 创建分片队列。shards 分片个数，capacity 每个分片的长度，opts 同 New，作用于每个分片。
+func NewSharded[E any](shards int, capacity uint32, opts ...Option) *ShardedQueue[E] {
+	q := &ShardedQueue[E]{shards: make([]*Queue[E], shards)}
+	for i := range q.shards {
+		q.shards[i] = New[E](capacity, opts...)
+	}
+	return q
+}
+
+// Put 填充数据。依次尝试各分片，起始分片轮流选取。返回所在分片剩余可填充数据个数。所有分片均已满时返回错误 ErrQueueIsFull。
+func (q *ShardedQueue[E]) Put(value E) (uint32, error) {
+	start := atomic.AddUint32(&q.putNext, 1)
+	var err error
+	for i := range q.shards {
+		var left uint32
+		if left, err = q.shard(start, i).Put(value); err == nil {
+			return left, nil
+		}
+	}
+	return 0, err
+}
+
+// Get 取出数据。依次尝试各分片，起始分片轮流选取。返回数据，所在分片剩余可取个数。所有分片均无数据时返回错误 ErrQueueIsEmpty。
+func (q *ShardedQueue[E]) Get() (E, uint32, error) {
+	start := atomic.AddUint32(&q.getNext, 1)
+	var (
+		val  E
+		used uint32
+		err  error
+	)
+	for i := range q.shards {
+		if val, used, err = q.shard(start, i).Get(); err == nil {
+			return val, used, nil
+		}
+	}
+	return val, 0, err
+}
+
+// GetEnough 从各分片合并取出至多 size 个数据。返回数据，实际取出数据个数，剩余可取数据个数。
+func (q *ShardedQueue[E]) GetEnough(size uint32) ([]E, uint32, uint32) {
+	start := atomic.AddUint32(&q.getNext, 1)
+	capacity := size
+	if n := q.Len(); capacity > n {
+		capacity = n
+	}
+	res := make([]E, 0, capacity)
+	for i := range q.shards {
+		if uint32(len(res)) == size {
+			break
+		}
+		vals, _, _ := q.shard(start, i).GetEnough(size - uint32(len(res)))
+		res = append(res, vals...)
+	}
+	return res, uint32(len(res)), q.Len()
+}
+
+// Cap 返回所有分片的总长度。
+func (q *ShardedQueue[E]) Cap() uint32 {
+	var n uint32
+	for _, s := range q.shards {
+		n += s.Cap()
+	}
+	return n
+}
+
+// Len 返回所有分片的数据个数之和。
+func (q *ShardedQueue[E]) Len() uint32 {
+	var n uint32
+	for _, s := range q.shards {
+		n += s.Len()
+	}
+	return n
+}
+
+// IsEmpty 判断队列是否有数据。
+func (q *ShardedQueue[E]) IsEmpty() bool {
+	for _, s := range q.shards {
+		if !s.IsEmpty() {
+			return false
+		}
+	}
+	return true
+}
+
+// Close 关闭所有分片。
+func (q *ShardedQueue[E]) Close() {
+	for _, s := range q.shards {
+		s.Close()
+	}
+}
+
+// 从 start 起第 i 个分片。
+func (q *ShardedQueue[E]) shard(start uint32, i int) *Queue[E] {
+	return q.shards[(int(start%uint32(len(q.shards)))+i)%len(q.shards)]
+}
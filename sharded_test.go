@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestShardedQueue(t *testing.T) {
+	const (
+		producers = 16
+		count     = 200
+	)
+	q := queue.NewSharded[int](4, 1024)
+	if q.Cap() != 4*1024 {
+		t.Fatal("Cap mismatch")
+	}
+	wg := sync.WaitGroup{}
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= count; i++ {
+				if _, err := q.Put(i); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if q.Len() != producers*count {
+		t.Fatal("Len mismatch")
+	}
+	sum := 0
+	vals, n, _ := q.GetEnough(100)
+	if n != 100 {
+		t.Fatal("n != 100")
+	}
+	for _, v := range vals {
+		sum += v
+	}
+	for !q.IsEmpty() {
+		val, _, err := q.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum += val
+	}
+	if sum != producers*count*(count+1)/2 {
+		t.Fatalf("sum %d mismatch", sum)
+	}
+	if vals, _, _ = q.GetEnough(math.MaxUint32); len(vals) != 0 || cap(vals) != 0 {
+		t.Fatal("GetEnough over-allocated")
+	}
+
+	q = queue.NewSharded[int](2, 2)
+	for i := 0; i < 4; i++ {
+		if _, err := q.Put(i); err != nil {
+			t.Fatal("shard spill failed")
+		}
+	}
+	if _, err := q.Put(4); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// SpmcQueue 单生产者多消费者队列。使用 NewSpmc 创建变量。
+//
+// 只允许一个协程填充数据，多个协程可同时取出数据。生产者独占尾部位置无需 CAS，消费者只竞争头部位置。
+// 违反单生产者约定将导致数据错乱。
+type SpmcQueue[E any] struct {
+	capacity, mask uint32
+	_              [cacheLinePadSize - 8]byte
+	head           uint32
+	_              [cacheLinePadSize - 4]byte
+	tail           uint32
+	_              [cacheLinePadSize - 4]byte
+	slots          []seqSlot[E]
+}
+
+// NewSpmc 创建单生产者多消费者队列。capacity 队列长度，调整规则同 New。
+func NewSpmc[E any](capacity uint32) *SpmcQueue[E] {
+	capacity = roundCapacity(capacity)
+	q := &SpmcQueue[E]{
+		capacity: capacity,
+		mask:     capacity - 1,
+		slots:    make([]seqSlot[E], capacity),
+	}
+	for i := range q.slots {
+		q.slots[i].seq = uint32(i)
+	}
+	return q
+}
+
+// Put 向队列尾部填充数据。返回剩余可填充数据个数。若队列已满返回错误 ErrQueueIsFull。仅允许生产者调用。
+//
+// 消费者已占用位置但尚未读完数据时，视为已满。
+func (q *SpmcQueue[E]) Put(value E) (uint32, error) {
+	tail := q.tail
+	slot := &q.slots[tail&q.mask]
+	if atomic.LoadUint32(&slot.seq) != tail {
+		return 0, ErrQueueIsFull
+	}
+	slot.value = value
+	atomic.StoreUint32(&slot.seq, tail+1)
+	atomic.StoreUint32(&q.tail, tail+1)
+	return q.capacity - (tail + 1 - atomic.LoadUint32(&q.head)), nil
+}
+
+// Get 取出队列头部数据。返回队列数据，队列剩余可取个数。当无数据可取时返回错误 ErrQueueIsEmpty。
+func (q *SpmcQueue[E]) Get() (E, uint32, error) {
+	for {
+		head := atomic.LoadUint32(&q.head)
+		slot := &q.slots[head&q.mask]
+		switch dif := int32(atomic.LoadUint32(&slot.seq) - (head + 1)); {
+		case dif == 0:
+			if atomic.CompareAndSwapUint32(&q.head, head, head+1) {
+				val := slot.value
+				var empty E
+				slot.value = empty
+				atomic.StoreUint32(&slot.seq, head+q.capacity)
+				return val, atomic.LoadUint32(&q.tail) - head - 1, nil
+			}
+		case dif < 0:
+			var empty E
+			return empty, 0, ErrQueueIsEmpty
+		default:
+			runtime.Gosched()
+		}
+	}
+}
+
+// Cap 返回队列长度。
+func (q *SpmcQueue[E]) Cap() uint32 {
+	return q.capacity
+}
+
+// Len 返回队列数据个数。
+func (q *SpmcQueue[E]) Len() uint32 {
+	head := atomic.LoadUint32(&q.head)
+	return atomic.LoadUint32(&q.tail) - head
+}
+
+// IsEmpty 判断队列是否有数据。
+func (q *SpmcQueue[E]) IsEmpty() bool {
+	return q.Len() == 0
+}
+
+// IsFull 判断队列是否已满。
+func (q *SpmcQueue[E]) IsFull() bool {
+	return q.Len() == q.capacity
+}
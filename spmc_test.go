@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestSpmcQueue(t *testing.T) {
+	q := queue.NewSpmc[int](2)
+	if _, _, err := q.Get(); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+	q.Put(1)
+	if left, _ := q.Put(2); left != 0 {
+		t.Fatal("left != 0")
+	}
+	if _, err := q.Put(3); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	if val, used, _ := q.Get(); val != 1 || used != 1 {
+		t.Fatal("Get mismatch")
+	}
+	if q.Len() != 1 {
+		t.Fatal("Len != 1")
+	}
+}
+
+func TestSpmcQueueConcurrent(t *testing.T) {
+	const (
+		consumers = 8
+		count     = 80000
+	)
+	q := queue.NewSpmc[int](64)
+	var (
+		sum      int64
+		received int64
+	)
+	wg := sync.WaitGroup{}
+	for c := 0; c < consumers; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt64(&received) < count {
+				val, _, err := q.Get()
+				if err != nil {
+					runtime.Gosched()
+					continue
+				}
+				atomic.AddInt64(&sum, int64(val))
+				atomic.AddInt64(&received, 1)
+			}
+		}()
+	}
+	for i := 1; i <= count; {
+		if _, err := q.Put(i); err == nil {
+			i++
+		} else {
+			runtime.Gosched()
+		}
+	}
+	wg.Wait()
+	if sum != count*(count+1)/2 {
+		t.Fatalf("sum %d mismatch", sum)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import "sync/atomic"
+
+// SpscQueue 单生产者单消费者队列。使用 NewSpsc 创建变量。
+//
+// 只允许一个协程填充数据、一个协程取出数据，头尾位置各自只有一个写者，无需 CAS。违反约定将导致数据错乱。
+type SpscQueue[E any] struct {
+	capacity, mask uint32
+	_              [cacheLinePadSize - 8]byte
+	head           uint32
+	cachedTail     uint32 // 消费者缓存的尾部位置，减少读取生产者所在缓存行。
+	_              [cacheLinePadSize - 8]byte
+	tail           uint32
+	cachedHead     uint32 // 生产者缓存的头部位置，减少读取消费者所在缓存行。
+	_              [cacheLinePadSize - 8]byte
+	elements       []E
+}
+
+// NewSpsc 创建单生产者单消费者队列。capacity 队列长度，调整规则同 New。
+func NewSpsc[E any](capacity uint32) *SpscQueue[E] {
+	capacity = roundCapacity(capacity)
+	return &SpscQueue[E]{
+		capacity: capacity,
+		mask:     capacity - 1,
+		elements: make([]E, capacity),
+	}
+}
+
+// Put 向队列尾部填充数据。返回剩余可填充数据个数，该值可能偏小。若队列已满返回错误 ErrQueueIsFull。仅允许生产者调用。
+func (q *SpscQueue[E]) Put(value E) (uint32, error) {
+	tail := q.tail
+	if tail-q.cachedHead == q.capacity {
+		q.cachedHead = atomic.LoadUint32(&q.head)
+		if tail-q.cachedHead == q.capacity {
+			return 0, ErrQueueIsFull
+		}
+	}
+	q.elements[tail&q.mask] = value
+	atomic.StoreUint32(&q.tail, tail+1)
+	return q.capacity - (tail + 1 - q.cachedHead), nil
+}
+
+// PutEnough 向队列填充多个数据。返回实际填充数据个数，剩余可填充数据个数。仅允许生产者调用。
+func (q *SpscQueue[E]) PutEnough(values ...E) (uint32, uint32) {
+	tail := q.tail
+	q.cachedHead = atomic.LoadUint32(&q.head)
+	left := q.capacity - (tail - q.cachedHead)
+	size := uint32(len(values))
+	if size > left {
+		size = left
+	}
+	for i := uint32(0); i < size; i++ {
+		q.elements[(tail+i)&q.mask] = values[i]
+	}
+	atomic.StoreUint32(&q.tail, tail+size)
+	return size, left - size
+}
+
+// Get 取出队列头部数据。返回队列数据，队列剩余可取个数，该值可能偏小。当无数据可取时返回错误 ErrQueueIsEmpty。仅允许消费者调用。
+func (q *SpscQueue[E]) Get() (E, uint32, error) {
+	head := q.head
+	if q.cachedTail == head {
+		q.cachedTail = atomic.LoadUint32(&q.tail)
+		if q.cachedTail == head {
+			var empty E
+			return empty, 0, ErrQueueIsEmpty
+		}
+	}
+	elem := &q.elements[head&q.mask]
+	val := *elem
+	var empty E
+	*elem = empty
+	atomic.StoreUint32(&q.head, head+1)
+	return val, q.cachedTail - head - 1, nil
+}
+
+// GetEnough 从队列取出多个数据。返回队列数据，实际取出数据个数，剩余可取数据个数。仅允许消费者调用。
+func (q *SpscQueue[E]) GetEnough(size uint32) ([]E, uint32, uint32) {
+	head := q.head
+	q.cachedTail = atomic.LoadUint32(&q.tail)
+	used := q.cachedTail - head
+	if size > used {
+		size = used
+	}
+	res := make([]E, size)
+	var empty E
+	for i := uint32(0); i < size; i++ {
+		elem := &q.elements[(head+i)&q.mask]
+		res[i] = *elem
+		*elem = empty
+	}
+	atomic.StoreUint32(&q.head, head+size)
+	return res, size, used - size
+}
+
+// Cap 返回队列长度。
+func (q *SpscQueue[E]) Cap() uint32 {
+	return q.capacity
+}
+
+// Len 返回队列数据个数。
+func (q *SpscQueue[E]) Len() uint32 {
+	head := atomic.LoadUint32(&q.head)
+	return atomic.LoadUint32(&q.tail) - head
+}
+
+// IsEmpty 判断队列是否有数据。
+func (q *SpscQueue[E]) IsEmpty() bool {
+	return q.Len() == 0
+}
+
+// IsFull 判断队列是否已满。
+func (q *SpscQueue[E]) IsFull() bool {
+	return q.Len() == q.capacity
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"runtime"
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestSpscQueue(t *testing.T) {
+	q := queue.NewSpsc[int](4)
+	if _, _, err := q.Get(); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+	if n, left := q.PutEnough(1, 2, 3); n != 3 || left != 1 {
+		t.Fatal("PutEnough mismatch")
+	}
+	if _, err := q.Put(4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Put(5); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	if val, _, _ := q.Get(); val != 1 {
+		t.Fatal("val != 1")
+	}
+	vals, n, used := q.GetEnough(8)
+	if n != 3 || used != 0 || vals[0] != 2 || vals[2] != 4 {
+		t.Fatal("GetEnough mismatch")
+	}
+	if !q.IsEmpty() || q.Cap() != 4 {
+		t.Fatal("queue state mismatch")
+	}
+}
+
+func TestSpscQueueConcurrent(t *testing.T) {
+	const count = 200000
+	q := queue.NewSpsc[int](64)
+	go func() {
+		for i := 0; i < count; {
+			if _, err := q.Put(i); err == nil {
+				i++
+			} else {
+				runtime.Gosched()
+			}
+		}
+	}()
+	for i := 0; i < count; {
+		val, _, err := q.Get()
+		if err != nil {
+			runtime.Gosched()
+			continue
+		}
+		if val != i {
+			t.Fatalf("val %d != %d", val, i)
+		}
+		i++
+	}
+}
+
+func BenchmarkSpscQueue(b *testing.B) {
+	q := queue.NewSpsc[int](1024)
+	go func() {
+		for i := 0; i < b.N; {
+			if _, err := q.Put(i); err == nil {
+				i++
+			} else {
+				runtime.Gosched()
+			}
+		}
+	}()
+	for i := 0; i < b.N; {
+		if _, _, err := q.Get(); err == nil {
+			i++
+		} else {
+			runtime.Gosched()
+		}
+	}
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type (
+	// Stack 无锁有界栈，后进先出。使用 NewStack 创建变量。
+	//
+	// 节点预先分配，分别串在栈链表和空闲链表上。链表头部为带版本号的节点下标，CAS 时一并比较版本号以避免 ABA 问题。
+	Stack[E any] struct {
+		capacity uint32
+		_        [cacheLinePadSize - 4]byte
+		top      uint64
+		_        [cacheLinePadSize - 8]byte
+		free     uint64
+		_        [cacheLinePadSize - 8]byte
+		length   uint32
+		_        [cacheLinePadSize - 4]byte
+		nodes    []stackNode[E]
+		notEmpty notifier
+		notFull  notifier
+	}
+
+	stackNode[E any] struct {
+		next  uint32 // 下一节点下标加一，零表示链表末尾。
+		value E
+	}
+)
+
+// NewStack 创建栈。capacity 栈长度，调整规则同 New。
+func NewStack[E any](capacity uint32) *Stack[E] {
+	capacity = roundCapacity(capacity)
+	s := &Stack[E]{
+		capacity: capacity,
+		nodes:    make([]stackNode[E], capacity),
+		free:     1,
+	}
+	for i := uint32(0); i < capacity-1; i++ {
+		s.nodes[i].next = i + 2
+	}
+	return s
+}
+
+// Push 向栈顶压入数据。返回剩余可压入数据个数。若栈已满返回错误 ErrQueueIsFull。
+func (s *Stack[E]) Push(value E) (uint32, error) {
+	n, ok := s.popList(&s.free)
+	if !ok {
+		return 0, ErrQueueIsFull
+	}
+	s.nodes[n].value = value
+	left := s.capacity - atomic.AddUint32(&s.length, 1)
+	s.pushList(&s.top, n)
+	s.notEmpty.notify()
+	return left, nil
+}
+
+// Pop 弹出栈顶数据。返回数据，栈剩余数据个数。当无数据可取时返回错误 ErrQueueIsEmpty。
+func (s *Stack[E]) Pop() (E, uint32, error) {
+	var empty E
+	n, ok := s.popList(&s.top)
+	if !ok {
+		return empty, 0, ErrQueueIsEmpty
+	}
+	val := s.nodes[n].value
+	s.nodes[n].value = empty
+	used := atomic.AddUint32(&s.length, ^uint32(0))
+	s.pushList(&s.free, n)
+	s.notFull.notify()
+	return val, used, nil
+}
+
+// TryPop 弹出栈顶数据。栈为空时返回 false。
+func (s *Stack[E]) TryPop() (E, bool) {
+	val, _, err := s.Pop()
+	return val, err == nil
+}
+
+// PushEnough 依次压入多个数据，最后一个数据位于栈顶。返回实际压入数据个数，剩余可压入数据个数。
+func (s *Stack[E]) PushEnough(values ...E) (uint32, uint32) {
+	var n, left uint32
+	for _, v := range values {
+		l, err := s.Push(v)
+		if err != nil {
+			return n, 0
+		}
+		n, left = n+1, l
+	}
+	if n == 0 {
+		left = s.capacity - s.Len()
+	}
+	return n, left
+}
+
+// PopEnough 依次弹出多个数据。返回数据，实际弹出数据个数，栈剩余数据个数。
+func (s *Stack[E]) PopEnough(size uint32) ([]E, uint32, uint32) {
+	if size > s.capacity {
+		size = s.capacity
+	}
+	res := make([]E, 0, size)
+	for uint32(len(res)) < size {
+		val, _, err := s.Pop()
+		if err != nil {
+			break
+		}
+		res = append(res, val)
+	}
+	return res, uint32(len(res)), s.Len()
+}
+
+// MustPush 向栈顶压入数据，若栈已满将等待，直至有数据被弹出。返回剩余可压入数据个数。
+func (s *Stack[E]) MustPush(value E) uint32 {
+	var left uint32
+	_ = await(context.Background(), &s.notFull, func() bool {
+		var err error
+		left, err = s.Push(value)
+		return err == nil
+	})
+	return left
+}
+
+// MustPop 弹出栈顶数据，若栈为空将等待，直至有数据压入。返回数据，栈剩余数据个数。
+func (s *Stack[E]) MustPop() (E, uint32) {
+	var (
+		val  E
+		used uint32
+	)
+	_ = await(context.Background(), &s.notEmpty, func() bool {
+		var err error
+		val, used, err = s.Pop()
+		return err == nil
+	})
+	return val, used
+}
+
+// Cap 返回栈长度。
+func (s *Stack[E]) Cap() uint32 {
+	return s.capacity
+}
+
+// Len 返回栈数据个数。
+func (s *Stack[E]) Len() uint32 {
+	return atomic.LoadUint32(&s.length)
+}
+
+// IsEmpty 判断栈是否有数据。
+func (s *Stack[E]) IsEmpty() bool {
+	return s.Len() == 0
+}
+
+// IsFull 判断栈是否已满。
+func (s *Stack[E]) IsFull() bool {
+	return s.Len() == s.capacity
+}
+
+// 从链表头部摘下一个节点，返回节点下标。链表为空时返回 false。
+func (s *Stack[E]) popList(head *uint64) (uint32, bool) {
+	for attempt := 0; ; attempt++ {
+		old := atomic.LoadUint64(head)
+		n := uint32(old)
+		if n == 0 {
+			return 0, false
+		}
+		next := atomic.LoadUint32(&s.nodes[n-1].next)
+		if atomic.CompareAndSwapUint64(head, old, (old>>32+1)<<32|uint64(next)) {
+			return n - 1, true
+		}
+		defaultBackoff.Wait(attempt)
+	}
+}
+
+// 将下标为 n 的节点放到链表头部。
+func (s *Stack[E]) pushList(head *uint64, n uint32) {
+	for attempt := 0; ; attempt++ {
+		old := atomic.LoadUint64(head)
+		atomic.StoreUint32(&s.nodes[n].next, uint32(old))
+		if atomic.CompareAndSwapUint64(head, old, (old>>32+1)<<32|uint64(n+1)) {
+			return
+		}
+		defaultBackoff.Wait(attempt)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestStack(t *testing.T) {
+	s := queue.NewStack[int](4)
+	if _, ok := s.TryPop(); ok {
+		t.Fatal("pop from empty stack")
+	}
+	if n, left := s.PushEnough(1, 2, 3, 4, 5); n != 4 || left != 0 {
+		t.Fatal("PushEnough mismatch")
+	}
+	if _, err := s.Push(5); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	if val, used, _ := s.Pop(); val != 4 || used != 3 {
+		t.Fatal("Pop mismatch")
+	}
+	vals, n, used := s.PopEnough(5)
+	if n != 3 || used != 0 || vals[0] != 3 || vals[2] != 1 {
+		t.Fatal("PopEnough mismatch")
+	}
+	if vals, _, _ = s.PopEnough(math.MaxUint32); len(vals) != 0 || cap(vals) > int(s.Cap()) {
+		t.Fatal("PopEnough over-allocated")
+	}
+
+	go s.MustPush(9)
+	if val, _ := s.MustPop(); val != 9 {
+		t.Fatal("val != 9")
+	}
+}
+
+func TestStackConcurrent(t *testing.T) {
+	const (
+		workers = 8
+		count   = 10000
+	)
+	s := queue.NewStack[int](16)
+	var sum int64
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= count; i++ {
+				s.MustPush(i)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				val, _ := s.MustPop()
+				atomic.AddInt64(&sum, int64(val))
+			}
+		}()
+	}
+	wg.Wait()
+	if sum != workers*count*(count+1)/2 {
+		t.Fatalf("sum %d mismatch", sum)
+	}
+}
@@ -0,0 +1,284 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+// Package testutil 提供队列的测试替身，便于对生产者、消费者逻辑进行确定性的单元测试。
+package testutil
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+// FakeQueue 可编排行为的队列，实现 queue.BlockingInterface。可指定第 n 次调用返回的错误、取出数据的延迟，并记录调用顺序。
+// 使用 NewFakeQueue 创建变量。
+type FakeQueue[E any] struct {
+	mu       sync.Mutex
+	capacity uint32
+	items    []E
+	closed   bool
+	calls    []string
+	counts   map[string]int
+	errs     map[string]map[int]error
+	getDelay time.Duration
+	onCall   func(op string)
+	notify   chan struct{}
+}
+
+// NewFakeQueue 创建测试队列。capacity 队列长度，不做调整。
+func NewFakeQueue[E any](capacity uint32) *FakeQueue[E] {
+	return &FakeQueue[E]{
+		capacity: capacity,
+		counts:   make(map[string]int),
+		errs:     make(map[string]map[int]error),
+		notify:   make(chan struct{}),
+	}
+}
+
+// FailOn 使方法 op 的第 n 次调用返回 err，而不操作数据，n 从1开始计数。op 为方法名，如 "Put"、"GetContext"。
+func (q *FakeQueue[E]) FailOn(op string, n int, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.errs[op] == nil {
+		q.errs[op] = make(map[int]error)
+	}
+	q.errs[op][n] = err
+}
+
+// SetGetDelay 使每次取出数据前等待 d。
+func (q *FakeQueue[E]) SetGetDelay(d time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.getDelay = d
+}
+
+// OnCall 设置每次调用被记录后的回调，参数为方法名，可用于在测试中等待某次调用发生后再进行下一步。
+// fn 在持有内部锁时调用，不可再调用该队列的方法。
+func (q *FakeQueue[E]) OnCall(fn func(op string)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.onCall = fn
+}
+
+// Calls 返回按调用顺序记录的方法名。
+func (q *FakeQueue[E]) Calls() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]string(nil), q.calls...)
+}
+
+// Items 返回队列当前数据的副本。
+func (q *FakeQueue[E]) Items() []E {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]E(nil), q.items...)
+}
+
+// Put 向队列尾部填充数据。错误语义同 queue.Queue.Put。
+func (q *FakeQueue[E]) Put(value E) (uint32, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.record("Put"); err != nil {
+		return q.left(), err
+	}
+	return q.put(value)
+}
+
+// Get 取出队列头部数据。错误语义同 queue.Queue.Get。
+func (q *FakeQueue[E]) Get() (E, uint32, error) {
+	q.delay()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.record("Get"); err != nil {
+		var empty E
+		return empty, uint32(len(q.items)), err
+	}
+	return q.get()
+}
+
+// PutEnough 向队列填充多个数据。返回实际填充数据个数，剩余可填充数据个数。
+func (q *FakeQueue[E]) PutEnough(values ...E) (uint32, uint32) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.record("PutEnough") != nil {
+		return 0, q.left()
+	}
+	var n uint32
+	for _, v := range values {
+		if _, err := q.put(v); err != nil {
+			break
+		}
+		n++
+	}
+	return n, q.left()
+}
+
+// GetEnough 从队列取出多个数据。返回队列数据，实际取出数据个数，剩余可取数据个数。
+func (q *FakeQueue[E]) GetEnough(size uint32) ([]E, uint32, uint32) {
+	q.delay()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.record("GetEnough") != nil {
+		return nil, 0, uint32(len(q.items))
+	}
+	if size > uint32(len(q.items)) {
+		size = uint32(len(q.items))
+	}
+	values := append([]E(nil), q.items[:size]...)
+	q.items = q.items[size:]
+	q.wake()
+	return values, size, uint32(len(q.items))
+}
+
+// PutContext 向队列尾部填充数据，队列已满时等待，直至 ctx 结束。
+func (q *FakeQueue[E]) PutContext(ctx context.Context, value E) (uint32, error) {
+	q.mu.Lock()
+	if err := q.record("PutContext"); err != nil {
+		q.mu.Unlock()
+		return 0, err
+	}
+	for {
+		left, err := q.put(value)
+		if err != queue.ErrQueueIsFull {
+			q.mu.Unlock()
+			return left, err
+		}
+		ch := q.notify
+		q.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-ch:
+		}
+		q.mu.Lock()
+	}
+}
+
+// GetContext 取出队列头部数据，队列无数据时等待，直至 ctx 结束。
+func (q *FakeQueue[E]) GetContext(ctx context.Context) (E, uint32, error) {
+	q.delay()
+	q.mu.Lock()
+	if err := q.record("GetContext"); err != nil {
+		q.mu.Unlock()
+		var empty E
+		return empty, 0, err
+	}
+	for {
+		val, used, err := q.get()
+		if err != queue.ErrQueueIsEmpty {
+			q.mu.Unlock()
+			return val, used, err
+		}
+		ch := q.notify
+		q.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return val, 0, ctx.Err()
+		case <-ch:
+		}
+		q.mu.Lock()
+	}
+}
+
+// Cap 返回队列长度。
+func (q *FakeQueue[E]) Cap() uint32 {
+	return q.capacity
+}
+
+// Len 返回队列数据个数。
+func (q *FakeQueue[E]) Len() uint32 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return uint32(len(q.items))
+}
+
+// IsEmpty 判断队列是否有数据。
+func (q *FakeQueue[E]) IsEmpty() bool {
+	return q.Len() == 0
+}
+
+// IsFull 判断队列是否已满。
+func (q *FakeQueue[E]) IsFull() bool {
+	return q.Len() >= q.capacity
+}
+
+// Close 关闭队列，语义同 queue.Queue.Close。
+func (q *FakeQueue[E]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	_ = q.record("Close")
+	q.closed = true
+	q.wake()
+}
+
+// 记录调用，返回为本次调用编排的错误。
+func (q *FakeQueue[E]) record(op string) error {
+	q.calls = append(q.calls, op)
+	q.counts[op]++
+	if q.onCall != nil {
+		q.onCall(op)
+	}
+	return q.errs[op][q.counts[op]]
+}
+
+func (q *FakeQueue[E]) put(value E) (uint32, error) {
+	if q.closed {
+		return 0, queue.ErrQueueIsClosed
+	}
+	if uint32(len(q.items)) >= q.capacity {
+		return 0, queue.ErrQueueIsFull
+	}
+	q.items = append(q.items, value)
+	q.wake()
+	return q.left(), nil
+}
+
+func (q *FakeQueue[E]) get() (E, uint32, error) {
+	var empty E
+	if len(q.items) == 0 {
+		if q.closed {
+			return empty, 0, queue.ErrQueueIsClosed
+		}
+		return empty, 0, queue.ErrQueueIsEmpty
+	}
+	val := q.items[0]
+	q.items[0] = empty
+	q.items = q.items[1:]
+	q.wake()
+	return val, uint32(len(q.items)), nil
+}
+
+func (q *FakeQueue[E]) left() uint32 {
+	if uint32(len(q.items)) >= q.capacity {
+		return 0
+	}
+	return q.capacity - uint32(len(q.items))
+}
+
+// 唤醒等待中的调用。
+func (q *FakeQueue[E]) wake() {
+	close(q.notify)
+	q.notify = make(chan struct{})
+}
+
+func (q *FakeQueue[E]) delay() {
+	q.mu.Lock()
+	d := q.getDelay
+	q.mu.Unlock()
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
+
+var _ queue.BlockingInterface[int] = (*FakeQueue[int])(nil)
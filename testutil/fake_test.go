@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package testutil_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+	"gitee.com/ivfzhou/safe-queue/testutil"
+)
+
+func TestFakeQueue(t *testing.T) {
+	errScripted := errors.New("scripted")
+	q := testutil.NewFakeQueue[int](4)
+	q.FailOn("Put", 2, errScripted)
+	q.Put(1)
+	if _, err := q.Put(2); err != errScripted || q.Len() != 1 {
+		t.Fatal("err != errScripted")
+	}
+	if _, err := q.Put(3); err != nil || !reflect.DeepEqual(q.Items(), []int{1, 3}) {
+		t.Fatal("items != [1 3]")
+	}
+	q.SetGetDelay(time.Millisecond * 10)
+	start := time.Now()
+	if val, _, _ := q.Get(); val != 1 || time.Since(start) < time.Millisecond*10 {
+		t.Fatal("get not delayed")
+	}
+	q.SetGetDelay(0)
+
+	blocked := make(chan struct{})
+	getContexts := 0
+	q.OnCall(func(op string) {
+		if op == "GetContext" {
+			if getContexts++; getContexts == 2 {
+				close(blocked)
+			}
+		}
+	})
+	go func() {
+		<-blocked
+		q.Close()
+	}()
+	if val, _, err := q.GetContext(context.Background()); err != nil || val != 3 {
+		t.Fatal("val != 3")
+	}
+	if _, _, err := q.GetContext(context.Background()); err != queue.ErrQueueIsClosed {
+		t.Fatal("err != ErrQueueIsClosed")
+	}
+	want := []string{"Put", "Put", "Put", "Get", "GetContext", "GetContext", "Close"}
+	if calls := q.Calls(); !reflect.DeepEqual(calls, want) {
+		t.Fatal(calls)
+	}
+}
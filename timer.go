@@ -0,0 +1,167 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	// TimerQueue 基于哈希时间轮的定时队列。数据在调度的时长之后到期，到期数据按到期顺序取出。使用 NewTimerQueue 创建变量。
+	//
+	// 时间轮以 tick 为精度，到期时刻向上取整到 tick 的整数倍。超过一圈的数据留在槽位中，转到其到期的那一圈时才被取出。
+	// 槽位存放在 Queue 中，头部为下一刻度的槽位，每推进一个刻度将头部槽位取出处理后放回尾部。
+	// 到期数据存放在 UnboundedQueue 中。相比 DelayQueue，调度为 O(1)，适合大量定时器。
+	TimerQueue[E any] struct {
+		mu      sync.Mutex
+		tick    time.Duration
+		start   time.Time
+		current uint64 // 已推进到的刻度。
+		pending uint32
+		mask    uint64
+		wheel   *Queue[*timerBucket[E]]
+		expired *UnboundedQueue[E]
+		changed notifier
+	}
+
+	timerBucket[E any] struct {
+		entries []timerEntry[E]
+	}
+
+	timerEntry[E any] struct {
+		due   uint64 // 到期的刻度。
+		value E
+	}
+)
+
+// NewTimerQueue 创建定时队列。tick 时间轮精度，小于等于零时使用一毫秒。slots 时间轮槽位个数，调整规则同 New 的 capacity。
+func NewTimerQueue[E any](tick time.Duration, slots uint32) *TimerQueue[E] {
+	if tick <= 0 {
+		tick = time.Millisecond
+	}
+	slots = roundCapacity(slots)
+	q := &TimerQueue[E]{
+		tick:    tick,
+		start:   time.Now(),
+		mask:    uint64(slots - 1),
+		wheel:   New[*timerBucket[E]](slots),
+		expired: NewUnbounded[E](64),
+	}
+	for i := uint32(0); i < slots; i++ {
+		q.wheel.Put(&timerBucket[E]{})
+	}
+	return q
+}
+
+// Schedule 调度数据在 delay 之后到期。delay 小于等于零时立即到期。
+func (q *TimerQueue[E]) Schedule(value E, delay time.Duration) {
+	q.mu.Lock()
+	due := uint64((time.Since(q.start) + delay + q.tick - 1) / q.tick)
+	if delay <= 0 || due <= q.current {
+		q.expired.Put(value)
+	} else {
+		// 头部槽位对应刻度 current+1，超过一圈的数据放入同余的槽位。
+		bucket, _ := q.wheel.At(uint32((due - q.current - 1) & q.mask))
+		bucket.entries = append(bucket.entries, timerEntry[E]{due: due, value: value})
+		q.pending++
+	}
+	q.mu.Unlock()
+	q.changed.notify()
+}
+
+// Poll 取出一个到期数据。返回数据，剩余已到期数据个数。无到期数据时返回错误 ErrQueueIsEmpty。
+func (q *TimerQueue[E]) Poll() (E, uint32, error) {
+	q.mu.Lock()
+	q.advance()
+	q.mu.Unlock()
+	return q.expired.Get()
+}
+
+// GetContext 取出一个到期数据，无到期数据时等待，直至有数据到期或 ctx 结束。返回数据，剩余已到期数据个数。ctx 结束时返回 ctx.Err()。
+func (q *TimerQueue[E]) GetContext(ctx context.Context) (E, uint32, error) {
+	timer := time.NewTimer(q.tick)
+	defer timer.Stop()
+	for {
+		ch := q.changed.wait()
+		if val, used, err := q.Poll(); err == nil {
+			return val, used, nil
+		}
+		q.mu.Lock()
+		next := time.Until(q.start.Add(time.Duration(q.current+1) * q.tick))
+		q.mu.Unlock()
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(next)
+		select {
+		case <-ch:
+		case <-timer.C:
+		case <-ctx.Done():
+			var empty E
+			return empty, 0, ctx.Err()
+		}
+	}
+}
+
+// Len 返回未到期和已到期未取出的数据个数之和。
+func (q *TimerQueue[E]) Len() uint32 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pending + q.expired.Len()
+}
+
+// 推进时间轮至当前刻度，将到期数据按到期顺序移入 expired。调用方须持有锁。
+func (q *TimerQueue[E]) advance() {
+	now := uint64(time.Since(q.start) / q.tick)
+	if now <= q.current {
+		return
+	}
+	steps, slots := now-q.current, q.mask+1
+	var batch []timerEntry[E]
+	// 不足一圈时按刻度顺序处理，槽位中到期的数据恰为该刻度到期，batch 已按到期顺序排列。
+	for i := uint64(0); i < steps && i < slots; i++ {
+		bucket, _, _ := q.wheel.Get()
+		kept := bucket.entries[:0]
+		for _, e := range bucket.entries {
+			if e.due <= now {
+				batch = append(batch, e)
+				q.pending--
+			} else {
+				kept = append(kept, e)
+			}
+		}
+		for j := len(kept); j < len(bucket.entries); j++ {
+			bucket.entries[j] = timerEntry[E]{}
+		}
+		bucket.entries = kept
+		q.wheel.Put(bucket)
+	}
+	if steps > slots {
+		// 超过一圈时各槽位均已处理，继续转动使头部对应刻度 now+1，并按到期刻度排序，同一刻度保持调度顺序。
+		for i := uint64(0); i < (steps-slots)&q.mask; i++ {
+			bucket, _, _ := q.wheel.Get()
+			q.wheel.Put(bucket)
+		}
+		sort.SliceStable(batch, func(i, j int) bool { return batch[i].due < batch[j].due })
+	}
+	for _, e := range batch {
+		q.expired.Put(e.value)
+	}
+	q.current = now
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestTimerQueue(t *testing.T) {
+	// 4 个槽位，每圈 8ms，30ms 的定时器需转多圈。
+	q := queue.NewTimerQueue[int](time.Millisecond*2, 4)
+	q.Schedule(3, time.Millisecond*30)
+	q.Schedule(1, time.Millisecond*5)
+	q.Schedule(2, time.Millisecond*15)
+	q.Schedule(0, 0)
+	if q.Len() != 4 {
+		t.Fatal("Len != 4")
+	}
+	if val, _, err := q.Poll(); err != nil || val != 0 {
+		t.Fatal("immediate timer not expired")
+	}
+	if _, _, err := q.Poll(); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+
+	start := time.Now()
+	for want := 1; want <= 3; want++ {
+		val, _, err := q.GetContext(context.Background())
+		if err != nil || val != want {
+			t.Fatalf("val %d != %d", val, want)
+		}
+	}
+	if time.Since(start) < time.Millisecond*28 {
+		t.Fatal("expired too early")
+	}
+
+	q.Schedule(4, time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if _, _, err := q.GetContext(ctx); err != context.DeadlineExceeded {
+		t.Fatal("err != DeadlineExceeded")
+	}
+}
+
+func TestTimerQueueOrderAcrossLaps(t *testing.T) {
+	// 4 个槽位，每圈 4ms。轮询间隔超过多圈时，到期数据仍按到期顺序取出。
+	q := queue.NewTimerQueue[int](time.Millisecond, 4)
+	q.Schedule(3, time.Millisecond*7)
+	q.Schedule(0, time.Millisecond*2)
+	q.Schedule(4, time.Millisecond*9)
+	q.Schedule(1, time.Millisecond*3)
+	q.Schedule(2, time.Millisecond*6)
+	q.Schedule(5, time.Millisecond*9)
+	time.Sleep(time.Millisecond * 20)
+	for want := 0; want <= 5; want++ {
+		if val, _, err := q.Poll(); err != nil || val != want {
+			t.Fatalf("val %d != %d", val, want)
+		}
+	}
+
+	q.Schedule(7, time.Millisecond*3)
+	q.Schedule(6, time.Millisecond*2)
+	for want := 6; want <= 7; want++ {
+		if val, _, err := q.GetContext(context.Background()); err != nil || val != want {
+			t.Fatalf("val %d != %d", val, want)
+		}
+	}
+	if q.Len() != 0 {
+		t.Fatal("Len != 0")
+	}
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import "context"
+
+// MirrorPolicy 镜像队列已满时的处理方式。
+type MirrorPolicy int
+
+const (
+	// MirrorBlock 等待镜像队列出现空位。
+	MirrorBlock MirrorPolicy = iota
+	// MirrorDrop 丢弃本次镜像数据。
+	MirrorDrop
+)
+
+// TransferOne 等待 src 有数据且 dst 有空位后，将 src 头部一个数据移入 dst。ctx 结束前未能移动时返回 ctx.Err()。
+// src 已关闭且无数据，或 dst 已关闭时返回 ErrQueueIsClosed；dst 暂停填充时返回 ErrQueuePaused。
+//
+// 数据从 src 取出后将在 ctx 结束前等待 dst 空位。若此时未能放入 dst，返回该数据、true 以及错误，由调用方处理，数据不会丢失。
+func TransferOne[E any](ctx context.Context, src, dst *Queue[E]) (E, bool, error) {
+	var empty E
+	for {
+		select {
+		case <-ctx.Done():
+			return empty, false, ctx.Err()
+		case <-src.NotEmpty():
+		}
+		select {
+		case <-ctx.Done():
+			return empty, false, ctx.Err()
+		case <-dst.NotFull():
+		}
+		if dst.isClosed() {
+			return empty, false, ErrQueueIsClosed
+		}
+		if dst.isPaused() && !dst.opts.blockWhenPaused {
+			return empty, false, ErrQueuePaused
+		}
+		val, _, err := src.Get()
+		if err == ErrQueueIsClosed {
+			return empty, false, err
+		}
+		if err == nil {
+			if _, err = dst.PutContext(ctx, val); err != nil {
+				return val, true, err
+			}
+			return empty, false, nil
+		}
+	}
+}
+
+// TransferTo 将队列头部至多 max 个数据按先进先出顺序批量移入 dst，两端均一次认领一批位置。返回移动数据个数，以及未能放入 dst 的数据。
+// 按 dst 当前空位数取出数据，无数据或 dst 无空位时返回0。dst 已关闭或暂停时返回相应错误，不取出数据。
+//
+// 若 dst 的空位被其他协程同时占用，将等待空位直至放入。等待期间 dst 被关闭、暂停或等待超过 SetMaxBlockDuration 设置的时长时，
+// 返回已放入个数、按原顺序排列的未放入数据以及相应错误，由调用方处理，数据不会丢失。
+func (q *Queue[E]) TransferTo(dst *Queue[E], max uint32) (uint32, []E, error) {
+	if dst.isClosed() {
+		return 0, nil, ErrQueueIsClosed
+	}
+	if dst.isPaused() && !dst.opts.blockWhenPaused {
+		return 0, nil, ErrQueuePaused
+	}
+	if free := dst.Cap() - dst.Len(); max > free {
+		max = free
+	}
+	if max == 0 {
+		return 0, nil, nil
+	}
+	values, n, _ := q.GetEnough(max)
+	if n == 0 {
+		return 0, nil, nil
+	}
+	put, _ := dst.PutEnough(values...)
+	if put < n {
+		done, err := dst.PutEnoughContext(context.Background(), values[put:]...)
+		put += done
+		if err != nil {
+			return put, values[put:], err
+		}
+	}
+	return put, nil, nil
+}
+
+// Tee 持续从 src 取出数据并调用 consume，同时将数据副本放入 mirror。mirror 已满时按 policy 处理。
+// ctx 结束时返回 ctx.Err()，src 关闭且数据取尽时返回 nil，mirror 已关闭时返回 ErrQueueIsClosed。
+//
+// 副本为值拷贝，指针、切片、映射等引用类型的副本与原数据共享底层内容。
+func Tee[E any](ctx context.Context, src, mirror *Queue[E], consume func(E), policy MirrorPolicy) error {
+	for {
+		val, _, err := src.GetContext(ctx)
+		if err == ErrQueueIsClosed {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		consume(val)
+		if err = teeMirror(ctx, mirror, val, policy); err != nil {
+			return err
+		}
+	}
+}
+
+func teeMirror[E any](ctx context.Context, mirror *Queue[E], value E, policy MirrorPolicy) error {
+	if policy == MirrorDrop {
+		if _, err := mirror.Put(value); err == ErrQueueIsClosed {
+			return err
+		}
+		return nil
+	}
+	_, err := mirror.PutContext(ctx, value)
+	return err
+}
+
+// DeadLetterPolicy 死信队列已满时的处理方式。
+type DeadLetterPolicy int
+
+const (
+	// DeadLetterBlock 等待死信队列出现空位。
+	DeadLetterBlock DeadLetterPolicy = iota
+	// DeadLetterDrop 丢弃该数据。
+	DeadLetterDrop
+	// DeadLetterError 丢弃该数据并返回死信队列的错误。
+	DeadLetterError
+)
+
+// GetOrDeadLetter 取出队列头部数据并调用 process 处理，处理失败时将数据放入死信队列 dlq，死信队列已满时按 policy 处理。
+//
+// 队列为空时返回 ErrQueueIsEmpty。处理成功返回 nil，处理失败返回 process 的错误。
+// 除 DeadLetterDrop 外，若数据未能放入死信队列，如 policy 为 DeadLetterError 且死信队列已满，或死信队列已关闭、暂停，
+// 返回该数据、true 以及死信队列的错误，由调用方处理，数据不会丢失。
+func (q *Queue[E]) GetOrDeadLetter(dlq *Queue[E], process func(E) error, policy DeadLetterPolicy) (E, bool, error) {
+	var empty E
+	val, _, err := q.Get()
+	if err != nil {
+		return empty, false, err
+	}
+	if err = process(val); err == nil {
+		return empty, false, nil
+	}
+	switch policy {
+	case DeadLetterBlock:
+		if _, dlqErr := dlq.PutContext(context.Background(), val); dlqErr != nil {
+			return val, true, dlqErr
+		}
+	case DeadLetterDrop:
+		_, _ = dlq.Put(val)
+	case DeadLetterError:
+		if _, dlqErr := dlq.Put(val); dlqErr != nil {
+			return val, true, dlqErr
+		}
+	}
+	return empty, false, err
+}
@@ -0,0 +1,234 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestTransferOne(t *testing.T) {
+	const count = 100
+	src := queue.New[int](4)
+	dst := queue.New[int](2)
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < count; i++ {
+			src.MustPut(i)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < count; i++ {
+			if i%10 == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			val, _ := dst.MustGet()
+			if val != i {
+				t.Errorf("val %d != %d", val, i)
+			}
+		}
+	}()
+	for i := 0; i < count; i++ {
+		if _, _, err := queue.TransferOne(context.Background(), src, dst); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	if _, _, err := queue.TransferOne(ctx, src, dst); err != context.DeadlineExceeded {
+		t.Fatal("err != DeadlineExceeded")
+	}
+
+	src.MustPut(1)
+	dst.PutEnough(1, 2)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	if _, _, err := queue.TransferOne(ctx, src, dst); err != context.DeadlineExceeded {
+		t.Fatal("err != DeadlineExceeded")
+	}
+	if src.Len() != 1 || dst.Len() != 2 {
+		t.Fatal("element moved after cancellation")
+	}
+
+	dst = queue.New[int](2)
+	dst.Pause()
+	if _, held, err := queue.TransferOne(context.Background(), src, dst); err != queue.ErrQueuePaused || held || src.Len() != 1 {
+		t.Fatal("err != ErrQueuePaused")
+	}
+	dst.Resume()
+	dst.Close()
+	if _, held, err := queue.TransferOne(context.Background(), src, dst); err != queue.ErrQueueIsClosed || held || src.Len() != 1 {
+		t.Fatal("err != ErrQueueIsClosed")
+	}
+
+	dst = queue.New[int](4, queue.WithWeights(func(v int) uint32 { return uint32(v) }, 5))
+	dst.Put(5)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	if val, held, err := queue.TransferOne(ctx, src, dst); err != context.DeadlineExceeded || !held || val != 1 {
+		t.Fatal("val not handed back")
+	}
+	if src.Len() != 0 || dst.Len() != 1 {
+		t.Fatal("len mismatch")
+	}
+}
+
+func TestTee(t *testing.T) {
+	src := queue.New[int](8)
+	mirror := queue.New[int](8)
+	src.PutEnough(1, 2, 3, 4, 5)
+	src.Close()
+
+	var consumed []int
+	err := queue.Tee(context.Background(), src, mirror, func(v int) { consumed = append(consumed, v) }, queue.MirrorBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(consumed) != 5 {
+		t.Fatal("len(consumed) != 5")
+	}
+	vals, size, _ := mirror.GetEnough(8)
+	if size != 5 {
+		t.Fatal("size != 5")
+	}
+	for i := range vals {
+		if consumed[i] != i+1 || vals[i] != i+1 {
+			t.Fatal("value != i+1")
+		}
+	}
+
+	src = queue.New[int](8)
+	mirror = queue.New[int](2)
+	src.PutEnough(1, 2, 3, 4)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	consumed = consumed[:0]
+	err = queue.Tee(ctx, src, mirror, func(v int) { consumed = append(consumed, v) }, queue.MirrorDrop)
+	if err != context.DeadlineExceeded {
+		t.Fatal("err != DeadlineExceeded")
+	}
+	if len(consumed) != 4 {
+		t.Fatal("len(consumed) != 4")
+	}
+	vals, _, _ = mirror.GetEnough(2)
+	if len(vals) != 2 || vals[0] != 1 || vals[1] != 2 {
+		t.Fatal("mirror != [1 2]")
+	}
+}
+
+func TestGetOrDeadLetter(t *testing.T) {
+	errOdd := errors.New("odd")
+	process := func(v int) error {
+		if v%2 == 1 {
+			return errOdd
+		}
+		return nil
+	}
+	q := queue.New[int](8)
+	dlq := queue.New[int](2)
+	if _, _, err := q.GetOrDeadLetter(dlq, process, queue.DeadLetterError); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+
+	q.PutEnough(2, 1, 4, 3, 5, 7)
+	if _, _, err := q.GetOrDeadLetter(dlq, process, queue.DeadLetterError); err != nil {
+		t.Fatal(err)
+	}
+	if dlq.Len() != 0 {
+		t.Fatal("dlq Len != 0")
+	}
+	if _, _, err := q.GetOrDeadLetter(dlq, process, queue.DeadLetterError); err != errOdd {
+		t.Fatal("err != errOdd")
+	}
+	if val, _, _ := dlq.Get(); val != 1 {
+		t.Fatal("dlq val != 1")
+	}
+	q.GetOrDeadLetter(dlq, process, queue.DeadLetterError)
+	q.GetOrDeadLetter(dlq, process, queue.DeadLetterError)
+	q.GetOrDeadLetter(dlq, process, queue.DeadLetterError)
+	if dlq.Len() != 2 {
+		t.Fatal("dlq Len != 2")
+	}
+	if val, held, err := q.GetOrDeadLetter(dlq, process, queue.DeadLetterError); err != queue.ErrQueueIsFull || !held || val != 7 {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	vals, _, _ := dlq.GetEnough(2)
+	if vals[0] != 3 || vals[1] != 5 {
+		t.Fatal("dlq vals != [3 5]")
+	}
+
+	dlq.Close()
+	q.Put(9)
+	if val, held, err := q.GetOrDeadLetter(dlq, process, queue.DeadLetterBlock); err != queue.ErrQueueIsClosed || !held || val != 9 {
+		t.Fatal("val not handed back")
+	}
+	q.Put(11)
+	if _, held, err := q.GetOrDeadLetter(dlq, process, queue.DeadLetterDrop); err != errOdd || held {
+		t.Fatal("err != errOdd")
+	}
+}
+
+func TestTransferTo(t *testing.T) {
+	src := queue.New[int](8)
+	dst := queue.New[int](4)
+	src.PutEnough(1, 2, 3, 4, 5, 6)
+	dst.Put(0)
+	if n, left, err := src.TransferTo(dst, 10); err != nil || n != 3 || left != nil {
+		t.Fatal("n != 3")
+	}
+	if n, _, _ := src.TransferTo(dst, 10); n != 0 {
+		t.Fatal("n != 0")
+	}
+	if values, _, _ := dst.GetEnough(4); values[0] != 0 || values[1] != 1 || values[3] != 3 {
+		t.Fatal("values != [0 1 2 3]")
+	}
+	if n, _, _ := src.TransferTo(dst, 2); n != 2 || src.Len() != 1 {
+		t.Fatal("n != 2")
+	}
+
+	dst.Close()
+	if n, _, err := src.TransferTo(dst, 1); err != queue.ErrQueueIsClosed || n != 0 || src.Len() != 1 {
+		t.Fatal("err != ErrQueueIsClosed")
+	}
+	dst = queue.New[int](4)
+	dst.Pause()
+	src.Put(7)
+	if n, _, err := src.TransferTo(dst, 4); err != queue.ErrQueuePaused || n != 0 || src.Len() != 2 {
+		t.Fatal("err != ErrQueuePaused")
+	}
+
+	src = queue.New[int](8)
+	src.PutEnough(3, 3, 1)
+	dst = queue.New[int](4, queue.WithWeights(func(v int) uint32 { return uint32(v) }, 5))
+	go func() {
+		for dst.Len() != 1 {
+			runtime.Gosched()
+		}
+		dst.Close()
+	}()
+	n, left, err := src.TransferTo(dst, 4)
+	if err != queue.ErrQueueIsClosed || n != 1 || len(left) != 2 || left[0] != 3 || left[1] != 1 || src.Len() != 0 {
+		t.Fatal("left != [3 1]")
+	}
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import "sync"
+
+type (
+	// UnboundedQueue 无界队列，由多个定长分段串联而成，填充数据永远不会因队列已满失败。使用 NewUnbounded 创建变量。
+	//
+	// 分段用尽后放回池中复用，以减少内存分配。内部使用互斥锁，适合不便估算容量的场景，对延迟敏感时应使用 Queue。
+	UnboundedQueue[E any] struct {
+		mu          sync.Mutex
+		head, tail  *segment[E]
+		read, write uint32 // 头部分段的读下标，尾部分段的写下标。
+		length      uint32
+		segmentSize uint32
+		pool        sync.Pool
+	}
+
+	segment[E any] struct {
+		values []E
+		next   *segment[E]
+	}
+)
+
+// NewUnbounded 创建无界队列。segmentSize 每个分段的长度，调整规则同 New 的 capacity。
+func NewUnbounded[E any](segmentSize uint32) *UnboundedQueue[E] {
+	q := &UnboundedQueue[E]{segmentSize: roundCapacity(segmentSize)}
+	q.pool.New = func() any {
+		return &segment[E]{values: make([]E, q.segmentSize)}
+	}
+	q.head = q.newSegment()
+	q.tail = q.head
+	return q
+}
+
+// Put 向队列尾部填充数据。返回队列数据个数。
+func (q *UnboundedQueue[E]) Put(value E) uint32 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.put(value)
+	return q.length
+}
+
+// PutEnough 向队列填充多个数据。返回队列数据个数。
+func (q *UnboundedQueue[E]) PutEnough(values ...E) uint32 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, v := range values {
+		q.put(v)
+	}
+	return q.length
+}
+
+// Get 取出队列头部数据。返回队列数据，队列剩余可取个数。当无数据可取时返回错误 ErrQueueIsEmpty。
+func (q *UnboundedQueue[E]) Get() (E, uint32, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.length == 0 {
+		var empty E
+		return empty, 0, ErrQueueIsEmpty
+	}
+	return q.get(), q.length, nil
+}
+
+// GetEnough 从队列取出多个数据。返回队列数据，实际取出数据个数，剩余可取数据个数。
+func (q *UnboundedQueue[E]) GetEnough(size uint32) ([]E, uint32, uint32) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if size > q.length {
+		size = q.length
+	}
+	res := make([]E, 0, size)
+	for i := uint32(0); i < size; i++ {
+		res = append(res, q.get())
+	}
+	return res, size, q.length
+}
+
+// Len 返回队列数据个数。
+func (q *UnboundedQueue[E]) Len() uint32 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.length
+}
+
+// IsEmpty 判断队列是否有数据。
+func (q *UnboundedQueue[E]) IsEmpty() bool {
+	return q.Len() == 0
+}
+
+func (q *UnboundedQueue[E]) put(value E) {
+	if q.write == q.segmentSize {
+		seg := q.newSegment()
+		q.tail.next = seg
+		q.tail = seg
+		q.write = 0
+	}
+	q.tail.values[q.write] = value
+	q.write++
+	q.length++
+}
+
+func (q *UnboundedQueue[E]) get() E {
+	if q.read == q.segmentSize {
+		seg := q.head
+		q.head = seg.next
+		q.read = 0
+		seg.next = nil
+		q.pool.Put(seg)
+	}
+	val := q.head.values[q.read]
+	var empty E
+	q.head.values[q.read] = empty
+	q.read++
+	q.length--
+	if q.length == 0 && q.head == q.tail {
+		q.read, q.write = 0, 0
+	}
+	return val
+}
+
+func (q *UnboundedQueue[E]) newSegment() *segment[E] {
+	return q.pool.Get().(*segment[E])
+}
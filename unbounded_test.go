@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"sync"
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestUnboundedQueue(t *testing.T) {
+	q := queue.NewUnbounded[int](4)
+	if _, _, err := q.Get(); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+	for i := 0; i < 100; i++ {
+		q.Put(i)
+	}
+	if q.Len() != 100 {
+		t.Fatal("Len != 100")
+	}
+	for i := 0; i < 50; i++ {
+		if val, _, err := q.Get(); err != nil || val != i {
+			t.Fatal("val != i")
+		}
+	}
+	if n := q.PutEnough(100, 101); n != 52 {
+		t.Fatal("Len != 52")
+	}
+	vals, size, used := q.GetEnough(60)
+	if size != 52 || used != 0 || vals[0] != 50 || vals[51] != 101 {
+		t.Fatal("GetEnough mismatch")
+	}
+	if !q.IsEmpty() {
+		t.Fatal("queue not empty")
+	}
+}
+
+func TestUnboundedQueueConcurrent(t *testing.T) {
+	const (
+		producers = 8
+		count     = 5000
+	)
+	q := queue.NewUnbounded[int](16)
+	wg := sync.WaitGroup{}
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= count; i++ {
+				q.Put(i)
+			}
+		}()
+	}
+	wg.Wait()
+	sum := 0
+	for !q.IsEmpty() {
+		val, _, _ := q.Get()
+		sum += val
+	}
+	if sum != producers*count*(count+1)/2 {
+		t.Fatalf("sum %d mismatch", sum)
+	}
+}
@@ -0,0 +1,529 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Backend 阻塞操作的等待方式。
+type Backend uint32
+
+const (
+	// SpinBackend 按等待策略循环等待，唤醒延迟低但持续占用 CPU。
+	SpinBackend Backend = iota
+	// ParkBackend 按等待策略短暂等待后挂起协程，待相反操作发生后唤醒，挂起期间不占用 CPU。默认使用。
+	ParkBackend
+	// FutexBackend 按等待策略短暂等待后通过 futex 阻塞所在线程，待相反操作发生后唤醒。
+	// 阻塞期间每隔 futexPollInterval 检查一次 ctx 是否结束。仅 Linux 支持，其它平台等同于 ParkBackend。
+	FutexBackend
+)
+
+const (
+	// 使用 ParkBackend 和 FutexBackend 时，挂起前按等待策略等待的次数。
+	spinsBeforePark = 16
+	// 使用 FutexBackend 时，单次阻塞的最长时长。
+	futexPollInterval = time.Millisecond * 10
+)
+
+type (
+	// WaitStrategy 等待策略，决定等待队列状态变化时如何消耗 CPU。用于阻塞操作以及等待其他协程完成位置读写。
+	WaitStrategy interface {
+		// Wait 在第 attempt 次（从零开始）检查条件不满足后调用。
+		Wait(attempt int)
+	}
+
+	// BusySpinWait 持续自旋，不让出处理器。唤醒延迟最低，仅适用于协程独占 CPU 的场景。
+	BusySpinWait struct{}
+
+	// YieldingWait 每次让出处理器。
+	YieldingWait struct{}
+
+	// SleepingWait 先让出处理器 Yields 次，之后每次休眠 Sleep。适用于对延迟不敏感的场景。
+	SleepingWait struct {
+		Yields int
+		Sleep  time.Duration
+	}
+
+	// BlockingWait 阻塞操作挂起协程等待唤醒，等待其他协程完成位置读写时让出处理器。
+	BlockingWait struct{}
+
+	// BackoffWait 指数退避：先自旋 Spins 次，再让出处理器 Yields 次，之后从 MinSleep 开始每次休眠时长加倍，最长 MaxSleep。
+	// 默认策略。竞争激烈时可避免大量协程同时让出处理器导致的性能骤降。
+	BackoffWait struct {
+		Spins, Yields      int
+		MinSleep, MaxSleep time.Duration
+	}
+)
+
+var defaultBackoff = BackoffWait{Spins: 8, Yields: 16, MinSleep: time.Microsecond, MaxSleep: time.Millisecond}
+
+// Wait 不做任何事。
+func (BusySpinWait) Wait(int) {}
+
+// Wait 让出处理器。
+func (YieldingWait) Wait(int) { runtime.Gosched() }
+
+// Wait 让出处理器或休眠。
+func (s SleepingWait) Wait(attempt int) {
+	if attempt < s.Yields {
+		runtime.Gosched()
+		return
+	}
+	time.Sleep(s.Sleep)
+}
+
+// Wait 让出处理器。
+func (BlockingWait) Wait(int) { runtime.Gosched() }
+
+// Wait 自旋、让出处理器或休眠。
+func (s BackoffWait) Wait(attempt int) {
+	switch {
+	case attempt < s.Spins:
+	case attempt < s.Spins+s.Yields:
+		runtime.Gosched()
+	default:
+		d := s.MaxSleep
+		if shift := attempt - s.Spins - s.Yields; shift < 32 && s.MinSleep<<uint(shift) < s.MaxSleep {
+			d = s.MinSleep << uint(shift)
+		}
+		time.Sleep(d)
+	}
+}
+
+// 通知等待者队列状态发生了变化。
+type notifier struct {
+	waiting      uint32
+	seq          uint32
+	futexWaiters int32
+	mu           sync.Mutex
+	ch           chan struct{}
+}
+
+// 已关闭的通道，表示条件已满足。
+var closedChan = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+// NotEmpty 返回一个通道，队列有数据或被关闭时该通道被关闭。队列当前已有数据或已关闭时，返回的通道已关闭。
+//
+// 每次调用返回的通道仅关闭一次，收到通知后取数据可能因其他协程抢先而失败，需再次调用 NotEmpty 等待。
+func (q *Queue[E]) NotEmpty() <-chan struct{} {
+	ch := q.notEmpty.wait()
+	if !q.IsEmpty() || q.isClosed() {
+		return closedChan
+	}
+	return ch
+}
+
+// NotFull 返回一个通道，队列有空位或被关闭时该通道被关闭。队列当前已有空位或已关闭时，返回的通道已关闭。
+//
+// 每次调用返回的通道仅关闭一次，收到通知后填充数据可能因其他协程抢先而失败，需再次调用 NotFull 等待。
+func (q *Queue[E]) NotFull() <-chan struct{} {
+	ch := q.notFull.wait()
+	if !q.IsFull() || q.isClosed() {
+		return closedChan
+	}
+	return ch
+}
+
+// SetBlockingBackend 设置阻塞操作的等待方式，可在运行时切换。已挂起的协程将被唤醒，并按新的方式继续等待。
+func (q *Queue[E]) SetBlockingBackend(b Backend) {
+	atomic.StoreUint32(&q.backend, uint32(b))
+	q.notEmpty.notify()
+	q.notFull.notify()
+}
+
+// PutContext 向队列尾部填充数据，若队列已满将等待，直至 ctx 结束。返回剩余可填充数据个数。
+// ctx 结束时返回 ctx.Err()，等待超过 SetMaxBlockDuration 设置的时长时返回 ErrBlockTimeout，以先到者为准。
+// 队列已关闭时返回 ErrQueueIsClosed，队列已暂停时返回 ErrQueuePaused，参见 WithBlockWhenPaused。
+func (q *Queue[E]) PutContext(ctx context.Context, value E) (uint32, error) {
+	var weight uint32
+	if q.weightOf != nil {
+		weight = q.weightOf(value)
+	}
+	deadline := q.blockDeadline()
+	for attempt := 0; ; attempt++ {
+		if q.acquireWeight(weight) {
+			position, _, left, err := q.acquirePut(1, 1)
+			if err == nil {
+				q.put(position, value)
+				q.leave()
+				return left, nil
+			}
+			q.releaseWeight(weight)
+			if err == q.errFull && q.grow() {
+				continue
+			}
+			if err == ErrQueueIsClosed || (err == ErrQueuePaused && !q.opts.blockWhenPaused) {
+				return 0, err
+			}
+		}
+		if err := q.checkWait(ctx, deadline); err != nil {
+			return 0, err
+		}
+		q.wait(ctx, attempt, &q.notFull, func() bool { return q.canPut(weight) || q.isClosed() }, deadline)
+	}
+}
+
+// GetContext 取出队列头部数据，若队列无数据将等待，直至 ctx 结束。返回队列数据，队列剩余可取个数。
+// ctx 结束时返回 ctx.Err()，等待超过 SetMaxBlockDuration 设置的时长时返回 ErrBlockTimeout，以先到者为准。
+// 队列已关闭且无数据时返回 ErrQueueIsClosed。
+func (q *Queue[E]) GetContext(ctx context.Context) (E, uint32, error) {
+	deadline := q.blockDeadline()
+	for attempt := 0; ; attempt++ {
+		position, _, used, err := q.acquireGet(1, 1)
+		if err == nil {
+			val := q.get(position)
+			q.leave()
+			q.maybeShrink(used)
+			return val, used, nil
+		}
+		if err == ErrQueueIsClosed {
+			var empty E
+			return empty, 0, err
+		}
+		if err = q.checkWait(ctx, deadline); err != nil {
+			var empty E
+			return empty, 0, err
+		}
+		q.wait(ctx, attempt, &q.notEmpty, func() bool { return !q.IsEmpty() || q.isClosed() }, deadline)
+	}
+}
+
+// PutTimeout 向队列尾部填充数据，若队列已满最多等待 d。返回剩余可填充数据个数。超时仍未填充时返回 ErrQueueIsFull。
+func (q *Queue[E]) PutTimeout(value E, d time.Duration) (uint32, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	left, err := q.PutContext(ctx, value)
+	if err == context.DeadlineExceeded {
+		err = q.fullError(q.errFull, 1)
+	}
+	return left, err
+}
+
+// GetTimeout 取出队列头部数据，若队列无数据最多等待 d。返回队列数据，队列剩余可取个数。超时仍无数据时返回 ErrQueueIsEmpty。
+func (q *Queue[E]) GetTimeout(d time.Duration) (E, uint32, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	val, used, err := q.GetContext(ctx)
+	if err == context.DeadlineExceeded {
+		err = q.errEmpty
+	}
+	return val, used, err
+}
+
+// PutFromChan 从 ch 逐个接收数据并填充到队列尾部，队列已满时等待。返回填充数据个数。
+// ch 关闭时返回 nil；ctx 结束或填充失败时返回的错误同 PutContext，此时已接收但未填充的数据被丢弃。
+func (q *Queue[E]) PutFromChan(ctx context.Context, ch <-chan E) (uint32, error) {
+	var n uint32
+	for {
+		select {
+		case <-ctx.Done():
+			return n, ctx.Err()
+		case v, ok := <-ch:
+			if !ok {
+				return n, nil
+			}
+			if _, err := q.PutContext(ctx, v); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+}
+
+// PutEnoughContext 向队列填充多个数据，空位不足时等待，直至全部填充或 ctx 结束。返回实际填充数据个数。
+// ctx 结束时返回 ctx.Err()，等待超过 SetMaxBlockDuration 设置的时长时返回 ErrBlockTimeout，以先到者为准。
+// 队列已关闭时返回 ErrQueueIsClosed，队列已暂停时返回 ErrQueuePaused，参见 WithBlockWhenPaused。
+//
+// 有空位时尽可能多地批量填充，多个协程同时填充时各自的数据可能交错。
+func (q *Queue[E]) PutEnoughContext(ctx context.Context, values ...E) (uint32, error) {
+	var done uint32
+	size := uint32(len(values))
+	deadline := q.blockDeadline()
+	for attempt := 0; done < size; {
+		if n, _ := q.PutEnough(values[done:]...); n > 0 {
+			done += n
+			attempt = 0
+			continue
+		}
+		if q.isClosed() {
+			return done, ErrQueueIsClosed
+		}
+		if q.isPaused() && !q.opts.blockWhenPaused {
+			return done, ErrQueuePaused
+		}
+		if err := q.checkWait(ctx, deadline); err != nil {
+			return done, err
+		}
+		var weight uint32
+		if q.weightOf != nil {
+			weight = q.weightOf(values[done])
+		}
+		q.wait(ctx, attempt, &q.notFull, func() bool { return q.canPut(weight) || q.isClosed() }, deadline)
+		attempt++
+	}
+	return done, nil
+}
+
+// GetEnoughContext 从队列取出 n 个数据，数据不足时等待，直至取满或 ctx 结束。返回取出的数据。
+// ctx 结束时返回已取出的数据和 ctx.Err()，等待超过 SetMaxBlockDuration 设置的时长时返回 ErrBlockTimeout，以先到者为准。
+// 队列已关闭且无数据时返回 ErrQueueIsClosed。
+//
+// 有数据时尽可能多地批量取出，多个协程同时取出时各自取得的数据可能不连续。需一次取出连续的 n 个数据时使用 GetExactly。
+func (q *Queue[E]) GetEnoughContext(ctx context.Context, n uint32) ([]E, error) {
+	res := make([]E, 0, q.capHint(n))
+	deadline := q.blockDeadline()
+	for attempt := 0; uint32(len(res)) < n; {
+		position, size, _, err := q.acquireGet(1, n-uint32(len(res)))
+		if err == nil {
+			for i, end := position, position+size; i != end; i++ {
+				res = append(res, q.get(i))
+			}
+			q.leave()
+			attempt = 0
+			continue
+		}
+		if err == ErrQueueIsClosed {
+			return res, err
+		}
+		if err = q.checkWait(ctx, deadline); err != nil {
+			return res, err
+		}
+		q.wait(ctx, attempt, &q.notEmpty, func() bool { return !q.IsEmpty() || q.isClosed() }, deadline)
+		attempt++
+	}
+	return res, nil
+}
+
+// GetExactly 从队列一次取出连续的 n 个数据，数据不足 n 个时等待，直至 ctx 结束。
+// ctx 结束时返回 ctx.Err()，等待超过 SetMaxBlockDuration 设置的时长时返回 ErrBlockTimeout，以先到者为准。
+// n 大于队列容量时永远无法满足，直接返回 ErrNotEnough。队列已关闭且剩余数据不足 n 个时返回 ErrQueueIsClosed，剩余数据仍留在队列中。
+func (q *Queue[E]) GetExactly(ctx context.Context, n uint32) ([]E, error) {
+	if n == 0 {
+		return []E{}, nil
+	}
+	if n > q.Cap() {
+		return nil, ErrNotEnough
+	}
+	deadline := q.blockDeadline()
+	for attempt := 0; ; attempt++ {
+		position, _, _, err := q.acquireGet(n, n)
+		if err == nil {
+			res := make([]E, 0, n)
+			for i, end := position, position+n; i != end; i++ {
+				res = append(res, q.get(i))
+			}
+			q.leave()
+			return res, nil
+		}
+		if q.isClosed() {
+			return nil, ErrQueueIsClosed
+		}
+		if err = q.checkWait(ctx, deadline); err != nil {
+			return nil, err
+		}
+		q.wait(ctx, attempt, &q.notEmpty, func() bool { return q.Len() >= n || q.isClosed() }, deadline)
+	}
+}
+
+// GetBatch 从队列取出一批数据，适合批量写库、批量发送等场景。队列无数据时等待，直至 ctx 结束。
+// 取得第一个数据后继续收集，取满 maxN 个或自取得第一个数据起经过 maxWait 时返回，期间队列关闭或 ctx 结束也将返回已收集的数据。
+// 未取得任何数据时返回的错误同 GetContext。
+func (q *Queue[E]) GetBatch(ctx context.Context, maxN uint32, maxWait time.Duration) ([]E, error) {
+	if maxN == 0 {
+		return []E{}, nil
+	}
+	val, _, err := q.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]E, 1, q.capHint(maxN))
+	res[0] = val
+	if maxN == 1 {
+		return res, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, maxWait)
+	defer cancel()
+	rest, _ := q.GetEnoughContext(ctx, maxN-1)
+	return append(res, rest...), nil
+}
+
+// 返回按 n 预分配切片时使用的长度，不超过队列容量，超出部分由 append 扩容。
+func (q *Queue[E]) capHint(n uint32) uint32 {
+	if c := q.Cap(); n > c {
+		return c
+	}
+	return n
+}
+
+// WaitUntilEmpty 等待队列数据全部被取出，直至 ctx 结束。ctx 结束时返回 ctx.Err()。
+func (q *Queue[E]) WaitUntilEmpty(ctx context.Context) error {
+	return q.waitUntil(ctx, &q.notFull, q.IsEmpty)
+}
+
+// WaitForSpace 等待队列至少有 n 个空位，直至 ctx 结束。ctx 结束时返回 ctx.Err()。
+// n 大于队列容量时永远无法满足，直接返回 ErrQueueIsFull。队列已关闭时返回 ErrQueueIsClosed。
+//
+// 返回后空位可能被其他协程占用，需配合 PutAtomic 等方法使用。
+func (q *Queue[E]) WaitForSpace(ctx context.Context, n uint32) error {
+	if n > q.Cap() {
+		return q.fullError(q.errFull, n)
+	}
+	err := q.waitUntil(ctx, &q.notFull, func() bool { return q.Cap()-q.Len() >= n || q.isClosed() })
+	if err == nil && q.isClosed() {
+		return ErrQueueIsClosed
+	}
+	return err
+}
+
+// 等待 ready 为真。n 为状态变化时通知的对象。
+func (q *Queue[E]) waitUntil(ctx context.Context, n *notifier, ready func() bool) error {
+	deadline := q.blockDeadline()
+	for attempt := 0; !ready(); attempt++ {
+		if err := q.checkWait(ctx, deadline); err != nil {
+			return err
+		}
+		q.wait(ctx, attempt, n, ready, deadline)
+	}
+	return nil
+}
+
+// 等待直至 ready 返回真，ctx 结束时返回 ctx.Err()。先按默认等待策略自旋，之后挂起协程等待 n 的通知。
+func await(ctx context.Context, n *notifier, ready func() bool) error {
+	for attempt := 0; !ready(); attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if attempt < spinsBeforePark {
+			defaultBackoff.Wait(attempt)
+			continue
+		}
+		ch := n.wait()
+		if ready() {
+			return nil
+		}
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// 判断是否应结束等待。
+func (q *Queue[E]) checkWait(ctx context.Context, deadline time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !deadline.IsZero() && !time.Now().Before(deadline) {
+		return ErrBlockTimeout
+	}
+	return nil
+}
+
+// 等待状态变化。attempt 为第几次等待，ready 判断是否已可操作，deadline 为零值表示不限时。
+func (q *Queue[E]) wait(ctx context.Context, attempt int, n *notifier, ready func() bool, deadline time.Time) {
+	if attempt < spinsBeforePark {
+		q.strategy.Wait(attempt)
+		return
+	}
+	switch Backend(atomic.LoadUint32(&q.backend)) {
+	case SpinBackend:
+		q.strategy.Wait(attempt)
+		return
+	case FutexBackend:
+		if futexSupported {
+			timeout := futexPollInterval
+			if d := time.Until(deadline); !deadline.IsZero() && d < timeout {
+				timeout = d
+			}
+			n.futexWait(ready, timeout)
+			return
+		}
+	}
+	ch := n.wait()
+	if ready() {
+		return
+	}
+	if deadline.IsZero() {
+		select {
+		case <-ch:
+		case <-ctx.Done():
+		}
+		return
+	}
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-ch:
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+func (q *Queue[E]) canPut(weight uint32) bool {
+	if q.IsFull() || q.isPaused() {
+		return false
+	}
+	return q.weightOf == nil || weight <= q.maxWeight-atomic.LoadUint32(&q.weight)
+}
+
+// 返回状态变化时将被关闭的通道。调用方获取通道后须重新检查状态，以免错过通知。
+func (n *notifier) wait() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.ch == nil {
+		n.ch = make(chan struct{})
+		atomic.StoreUint32(&n.waiting, 1)
+	}
+	return n.ch
+}
+
+// 通过 futex 阻塞至被唤醒或超过 timeout。
+func (n *notifier) futexWait(ready func() bool, timeout time.Duration) {
+	atomic.AddInt32(&n.futexWaiters, 1)
+	defer atomic.AddInt32(&n.futexWaiters, -1)
+	seq := atomic.LoadUint32(&n.seq)
+	atomic.StoreUint32(&n.waiting, 1)
+	if ready() {
+		return
+	}
+	futexWait(&n.seq, seq, timeout)
+}
+
+// 唤醒所有等待者。
+func (n *notifier) notify() {
+	if atomic.LoadUint32(&n.waiting) == 0 {
+		return
+	}
+	n.mu.Lock()
+	atomic.StoreUint32(&n.waiting, 0)
+	if n.ch != nil {
+		close(n.ch)
+		n.ch = nil
+	}
+	n.mu.Unlock()
+	if atomic.LoadInt32(&n.futexWaiters) > 0 {
+		atomic.AddUint32(&n.seq, 1)
+		futexWake(&n.seq)
+	}
+}
@@ -0,0 +1,522 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"context"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestSetBlockingBackend(t *testing.T) {
+	const workers = 8
+	q := queue.New[int](4)
+	q.SetBlockingBackend(queue.ParkBackend)
+
+	var sum int64
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, _ := q.MustGet()
+			atomic.AddInt64(&sum, int64(val))
+		}()
+	}
+	time.Sleep(time.Millisecond * 20)
+	q.SetBlockingBackend(queue.SpinBackend)
+	for i := 1; i <= workers/2; i++ {
+		q.MustPut(i)
+	}
+	time.Sleep(time.Millisecond * 20)
+	q.SetBlockingBackend(queue.ParkBackend)
+	for i := workers/2 + 1; i <= workers; i++ {
+		q.MustPut(i)
+	}
+	wg.Wait()
+	if sum != workers*(workers+1)/2 {
+		t.Fatal("sum mismatch")
+	}
+
+	q.PutEnough(1, 2, 3, 4)
+	done := make(chan struct{})
+	go func() {
+		q.MustPut(5)
+		close(done)
+	}()
+	time.Sleep(time.Millisecond * 20)
+	q.SetBlockingBackend(queue.SpinBackend)
+	q.MustGet()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("blocked MustPut not woken")
+	}
+}
+
+func TestPutGetContext(t *testing.T) {
+	for _, backend := range []queue.Backend{queue.SpinBackend, queue.ParkBackend} {
+		q := queue.New[int](2)
+		q.SetBlockingBackend(backend)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+		if _, _, err := q.GetContext(ctx); err != context.DeadlineExceeded {
+			t.Fatal("err != DeadlineExceeded")
+		}
+		cancel()
+
+		go func() {
+			time.Sleep(time.Millisecond * 10)
+			q.Put(1)
+		}()
+		val, used, err := q.GetContext(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if val != 1 || used != 0 {
+			t.Fatal("val != 1 || used != 0")
+		}
+
+		q.PutEnough(1, 2)
+		ctx, cancel = context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(time.Millisecond * 10)
+			cancel()
+		}()
+		if _, err = q.PutContext(ctx, 3); err != context.Canceled {
+			t.Fatal("err != Canceled")
+		}
+
+		go func() {
+			time.Sleep(time.Millisecond * 10)
+			q.Get()
+		}()
+		left, err := q.PutContext(context.Background(), 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if left != 0 {
+			t.Fatal("left != 0")
+		}
+
+		q.SetMaxBlockDuration(time.Millisecond * 10)
+		ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+		if _, err = q.PutContext(ctx, 4); err != queue.ErrBlockTimeout {
+			t.Fatal("err != ErrBlockTimeout")
+		}
+		cancel()
+	}
+}
+
+func TestPutGetTimeout(t *testing.T) {
+	q := queue.New[int](2)
+	q.SetBlockingBackend(queue.ParkBackend)
+
+	start := time.Now()
+	if _, _, err := q.GetTimeout(time.Millisecond * 30); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+	if elapsed := time.Since(start); elapsed < time.Millisecond*30 || elapsed > time.Millisecond*500 {
+		t.Fatalf("elapsed %v", elapsed)
+	}
+
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		q.Put(1)
+	}()
+	if val, _, err := q.GetTimeout(time.Second); err != nil || val != 1 {
+		t.Fatal("GetTimeout failed")
+	}
+
+	q.PutEnough(1, 2)
+	if _, err := q.PutTimeout(3, time.Millisecond*30); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		q.Get()
+	}()
+	if left, err := q.PutTimeout(3, time.Second); err != nil || left != 0 {
+		t.Fatal("PutTimeout failed")
+	}
+}
+
+type countingWait struct{ calls int64 }
+
+func (w *countingWait) Wait(int) {
+	atomic.AddInt64(&w.calls, 1)
+	runtime.Gosched()
+}
+
+func TestWaitStrategy(t *testing.T) {
+	strategies := []queue.WaitStrategy{
+		queue.BusySpinWait{},
+		queue.YieldingWait{},
+		queue.SleepingWait{Yields: 4, Sleep: time.Microsecond * 50},
+		queue.BlockingWait{},
+	}
+	for _, s := range strategies {
+		q := queue.New[int](4, queue.WithWaitStrategy(s))
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				q.MustPut(i)
+			}
+		}()
+		for i := 0; i < 100; i++ {
+			if val, _ := q.MustGet(); val != i {
+				t.Fatalf("%T: val %d != %d", s, val, i)
+			}
+		}
+		wg.Wait()
+	}
+
+	w := &countingWait{}
+	q := queue.New[int](2, queue.WithWaitStrategy(w))
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		q.Put(1)
+	}()
+	q.MustGet()
+	if atomic.LoadInt64(&w.calls) == 0 {
+		t.Fatal("strategy not used")
+	}
+}
+
+func TestMustGetParks(t *testing.T) {
+	w := &countingWait{}
+	q := queue.New[int](2, queue.WithWaitStrategy(w))
+	done := make(chan int)
+	go func() {
+		val, _ := q.MustGet()
+		done <- val
+	}()
+	time.Sleep(time.Millisecond * 50)
+	calls := atomic.LoadInt64(&w.calls)
+	if calls == 0 || calls > 16 {
+		t.Fatalf("calls %d not in [1, 16]", calls)
+	}
+	q.MustPut(7)
+	if val := <-done; val != 7 {
+		t.Fatal("val != 7")
+	}
+
+	w = &countingWait{}
+	q = queue.New[int](2, queue.WithWaitStrategy(w), queue.WithBlockingBackend(queue.SpinBackend))
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		q.MustPut(1)
+	}()
+	q.MustGet()
+	if atomic.LoadInt64(&w.calls) <= 16 {
+		t.Fatal("spin backend parked")
+	}
+}
+
+func TestBackoffWait(t *testing.T) {
+	w := queue.BackoffWait{Spins: 2, Yields: 2, MinSleep: time.Millisecond, MaxSleep: time.Millisecond * 4}
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		w.Wait(i)
+	}
+	if time.Since(start) > time.Millisecond {
+		t.Fatal("spins or yields slept")
+	}
+	start = time.Now()
+	w.Wait(4)
+	w.Wait(5)
+	w.Wait(100)
+	if elapsed := time.Since(start); elapsed < time.Millisecond*7 {
+		t.Fatalf("elapsed %v < 7ms", elapsed)
+	}
+}
+
+func BenchmarkWaitStrategy(b *testing.B) {
+	strategies := map[string]queue.WaitStrategy{
+		"Yielding": queue.YieldingWait{},
+		"Backoff":  queue.BackoffWait{Spins: 8, Yields: 16, MinSleep: time.Microsecond, MaxSleep: time.Millisecond},
+	}
+	for name, s := range strategies {
+		b.Run(name, func(b *testing.B) {
+			q := queue.New[int](1<<10, queue.WithWaitStrategy(s))
+			b.SetParallelism(64)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					q.MustPut(1)
+					q.MustGet()
+				}
+			})
+		})
+	}
+}
+
+func TestFutexBackend(t *testing.T) {
+	const workers = 4
+	q := queue.New[int](2, queue.WithBlockingBackend(queue.FutexBackend))
+	var sum int64
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, _ := q.MustGet()
+			atomic.AddInt64(&sum, int64(val))
+		}()
+	}
+	time.Sleep(time.Millisecond * 30)
+	for i := 1; i <= workers; i++ {
+		q.MustPut(i)
+	}
+	wg.Wait()
+	if sum != workers*(workers+1)/2 {
+		t.Fatal("sum mismatch")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*30)
+	defer cancel()
+	start := time.Now()
+	if _, _, err := q.GetContext(ctx); err != context.DeadlineExceeded {
+		t.Fatal("err != DeadlineExceeded")
+	}
+	if elapsed := time.Since(start); elapsed > time.Millisecond*200 {
+		t.Fatalf("elapsed %v", elapsed)
+	}
+}
+
+func TestWaitUntilEmptyAndForSpace(t *testing.T) {
+	q := queue.New[int](8)
+	if err := q.WaitUntilEmpty(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	q.PutEnough(1, 2, 3, 4, 5, 6)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	if err := q.WaitUntilEmpty(ctx); err != context.DeadlineExceeded {
+		t.Fatal("err != DeadlineExceeded")
+	}
+	cancel()
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*20)
+	if err := q.WaitForSpace(ctx, 4); err != context.DeadlineExceeded {
+		t.Fatal("err != DeadlineExceeded")
+	}
+	cancel()
+	if err := q.WaitForSpace(context.Background(), 9); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+
+	go func() {
+		for i := 0; i < 6; i++ {
+			time.Sleep(time.Millisecond * 5)
+			q.Get()
+		}
+	}()
+	if err := q.WaitForSpace(context.Background(), 4); err != nil {
+		t.Fatal(err)
+	}
+	if free := q.Cap() - q.Len(); free < 4 {
+		t.Fatal("free < 4")
+	}
+	if err := q.WaitUntilEmpty(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !q.IsEmpty() {
+		t.Fatal("queue is not empty")
+	}
+}
+
+func TestNotEmptyNotFull(t *testing.T) {
+	q := queue.New[int](2)
+	select {
+	case <-q.NotEmpty():
+		t.Fatal("empty queue notified not empty")
+	case <-q.NotFull():
+	}
+
+	notEmpty := q.NotEmpty()
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		q.Put(1)
+	}()
+	select {
+	case <-notEmpty:
+	case <-time.After(time.Second):
+		t.Fatal("not notified")
+	}
+	if val, _, err := q.Get(); err != nil || val != 1 {
+		t.Fatal("Get failed")
+	}
+
+	q.PutEnough(1, 2)
+	notFull := q.NotFull()
+	select {
+	case <-notFull:
+		t.Fatal("full queue notified not full")
+	default:
+	}
+	q.Get()
+	select {
+	case <-notFull:
+	case <-time.After(time.Second):
+		t.Fatal("not notified")
+	}
+	select {
+	case <-q.NotEmpty():
+	default:
+		t.Fatal("non-empty queue not notified")
+	}
+}
+
+func TestPutGetEnoughContext(t *testing.T) {
+	q := queue.New[int](4)
+	values := make([]int, 100)
+	for i := range values {
+		values[i] = i
+	}
+	go q.MustPutEnough(values...)
+	got := q.MustGetEnough(100)
+	for i, v := range got {
+		if v != i {
+			t.Fatal("v != i")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	n, err := q.PutEnoughContext(ctx, values[:6]...)
+	if err != context.DeadlineExceeded || n != 4 {
+		t.Fatalf("n %d, err %v", n, err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	got, err = q.GetEnoughContext(ctx, 6)
+	if err != context.DeadlineExceeded || len(got) != 4 {
+		t.Fatalf("len %d, err %v", len(got), err)
+	}
+	q.PutEnough(1, 2)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	if got, err = q.GetEnoughContext(ctx, math.MaxUint32); len(got) != 2 || cap(got) > int(q.Cap()) {
+		t.Fatal("GetEnoughContext over-allocated")
+	}
+
+	q.PutEnough(1, 2)
+	q.Close()
+	if got, err = q.GetEnoughContext(context.Background(), 3); err != queue.ErrQueueIsClosed || len(got) != 2 {
+		t.Fatal("err != ErrQueueIsClosed")
+	}
+}
+
+func TestGetExactly(t *testing.T) {
+	q := queue.New[int](8)
+	if _, err := q.GetExactly(context.Background(), 9); err != queue.ErrNotEnough {
+		t.Fatal("err != ErrNotEnough")
+	}
+	q.PutEnough(1, 2)
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		q.MustPut(3)
+		time.Sleep(time.Millisecond * 10)
+		q.MustPut(4)
+	}()
+	vals, err := q.GetExactly(context.Background(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 4 || vals[0] != 1 || vals[3] != 4 {
+		t.Fatal("vals != [1 2 3 4]")
+	}
+
+	q.PutEnough(5, 6)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	if _, err = q.GetExactly(ctx, 3); err != context.DeadlineExceeded {
+		t.Fatal("err != DeadlineExceeded")
+	}
+	if q.Len() != 2 {
+		t.Fatal("partial batch taken")
+	}
+	q.Close()
+	if _, err = q.GetExactly(context.Background(), 3); err != queue.ErrQueueIsClosed {
+		t.Fatal("err != ErrQueueIsClosed")
+	}
+}
+
+func TestGetBatch(t *testing.T) {
+	q := queue.New[int](16)
+	q.PutEnough(1, 2, 3, 4, 5)
+	vals, err := q.GetBatch(context.Background(), 3, time.Second)
+	if err != nil || len(vals) != 3 || vals[2] != 3 {
+		t.Fatal("vals != [1 2 3]")
+	}
+
+	start := time.Now()
+	vals, err = q.GetBatch(context.Background(), 8, time.Millisecond*20)
+	if err != nil || len(vals) != 2 {
+		t.Fatal("vals != [4 5]")
+	}
+	if time.Since(start) < time.Millisecond*20 {
+		t.Fatal("returned before maxWait")
+	}
+	q.PutEnough(6, 7)
+	if vals, err = q.GetBatch(context.Background(), math.MaxUint32, time.Millisecond*10); err != nil || len(vals) != 2 || cap(vals) > 2*int(q.Cap()) {
+		t.Fatal("GetBatch over-allocated")
+	}
+
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		q.MustPut(6)
+		q.MustPut(7)
+	}()
+	vals, err = q.GetBatch(context.Background(), 2, time.Second)
+	if err != nil || len(vals) != 2 || vals[0] != 6 || vals[1] != 7 {
+		t.Fatal("vals != [6 7]")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if _, err = q.GetBatch(ctx, 2, time.Second); err != context.DeadlineExceeded {
+		t.Fatal("err != DeadlineExceeded")
+	}
+}
+
+func TestPutFromChan(t *testing.T) {
+	q := queue.New[int](4)
+	ch := make(chan int)
+	go func() {
+		for i := 1; i <= 6; i++ {
+			ch <- i
+		}
+		close(ch)
+	}()
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		q.GetEnough(2)
+	}()
+	if n, err := q.PutFromChan(context.Background(), ch); err != nil || n != 6 {
+		t.Fatal("n != 6")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if n, err := q.PutFromChan(ctx, make(chan int)); err != context.DeadlineExceeded || n != 0 {
+		t.Fatal("err != DeadlineExceeded")
+	}
+}
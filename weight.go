@@ -0,0 +1,64 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import "sync/atomic"
+
+// Weight 返回队列数据的总权重。未使用 WithWeights 时恒为零。
+func (q *Queue[E]) Weight() uint32 {
+	return atomic.LoadUint32(&q.weight)
+}
+
+func (q *Queue[E]) acquireWeight(weight uint32) bool {
+	if q.weightOf == nil {
+		return true
+	}
+	for {
+		old := atomic.LoadUint32(&q.weight)
+		if weight > q.maxWeight-old {
+			return false
+		}
+		if atomic.CompareAndSwapUint32(&q.weight, old, old+weight) {
+			return true
+		}
+	}
+}
+
+// 按顺序占用尽可能多的数据权重，返回占用的数据个数。
+func (q *Queue[E]) acquireWeights(values []E) uint32 {
+	for {
+		old := atomic.LoadUint32(&q.weight)
+		sum, size := uint32(0), uint32(0)
+		for _, v := range values {
+			w := q.weightOf(v)
+			if w > q.maxWeight-old-sum {
+				break
+			}
+			sum += w
+			size++
+		}
+		if size == 0 {
+			return 0
+		}
+		if atomic.CompareAndSwapUint32(&q.weight, old, old+sum) {
+			return size
+		}
+	}
+}
+
+func (q *Queue[E]) releaseWeight(weight uint32) {
+	if q.weightOf == nil || weight == 0 {
+		return
+	}
+	atomic.AddUint32(&q.weight, ^(weight - 1))
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"testing"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestWeights(t *testing.T) {
+	q := queue.New[string](8, queue.WithWeights(func(s string) uint32 { return uint32(len(s)) }, 10))
+
+	if _, err := q.Put("abcd"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Put("abcde"); err != nil {
+		t.Fatal(err)
+	}
+	if q.Weight() != 9 {
+		t.Fatal("Weight != 9")
+	}
+	if _, err := q.Put("ab"); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	if q.Len() != 2 {
+		t.Fatal("Len != 2")
+	}
+	if _, err := q.Put("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	val, _, err := q.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != "abcd" {
+		t.Fatal("val != abcd")
+	}
+	if q.Weight() != 6 {
+		t.Fatal("Weight != 6")
+	}
+
+	size, _ := q.PutEnough("ab", "a", "abc", "a")
+	if size != 2 {
+		t.Fatal("size != 2")
+	}
+	if q.Weight() != 9 {
+		t.Fatal("Weight != 9")
+	}
+
+	q.GetEnough(8)
+	if q.Weight() != 0 {
+		t.Fatal("Weight != 0")
+	}
+}
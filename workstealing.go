@@ -0,0 +1,176 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue
+
+import "sync/atomic"
+
+type (
+	// WorkStealing 工作窃取调度器的任务容器。使用 NewWorkStealing 创建变量。
+	//
+	// 每个工作者拥有一个双端队列，只有所属工作者可在底部填充和取出，其他工作者可无锁地从顶部窃取。
+	// 外部协程通过 Submit 将任务放入共享的注入队列。
+	WorkStealing[E any] struct {
+		injector *Queue[E]
+		workers  []*Worker[E]
+	}
+
+	// Worker 工作者。Push、Pop 只允许所属工作者协程调用。
+	Worker[E any] struct {
+		ws    *WorkStealing[E]
+		id    int
+		deque *stealDeque[E]
+	}
+
+	// Chase-Lev 有界双端队列。槽位存放数据指针，使窃取者读取数据时不与所属工作者写入竞争。
+	stealDeque[E any] struct {
+		top    int64
+		_      [cacheLinePadSize - 8]byte
+		bottom int64
+		_      [cacheLinePadSize - 8]byte
+		mask   int64
+		slots  []atomic.Pointer[E]
+		keep   bool
+	}
+)
+
+// NewWorkStealing 创建工作窃取容器。workers 工作者个数，capacity 每个工作者队列以及注入队列的长度，调整规则同 New。
+// opts 注入队列的配置项，其中 WithoutZeroing 同时作用于工作者队列。
+func NewWorkStealing[E any](workers int, capacity uint32, opts ...Option) *WorkStealing[E] {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	capacity = roundCapacity(capacity)
+	ws := &WorkStealing[E]{
+		injector: New[E](capacity, opts...),
+		workers:  make([]*Worker[E], workers),
+	}
+	for i := range ws.workers {
+		ws.workers[i] = &Worker[E]{
+			ws: ws,
+			id: i,
+			deque: &stealDeque[E]{
+				mask:  int64(capacity - 1),
+				slots: make([]atomic.Pointer[E], capacity),
+				keep:  o.noZeroing,
+			},
+		}
+	}
+	return ws
+}
+
+// Submit 从外部提交任务到注入队列。返回注入队列剩余可填充数据个数。注入队列已满时返回错误 ErrQueueIsFull。
+func (ws *WorkStealing[E]) Submit(value E) (uint32, error) {
+	return ws.injector.Put(value)
+}
+
+// Worker 返回第 i 个工作者。
+func (ws *WorkStealing[E]) Worker(i int) *Worker[E] {
+	return ws.workers[i]
+}
+
+// Len 返回所有队列的数据个数，并发操作时仅为近似值。
+func (ws *WorkStealing[E]) Len() uint32 {
+	n := ws.injector.Len()
+	for _, w := range ws.workers {
+		n += uint32(w.deque.len())
+	}
+	return n
+}
+
+// Push 将任务放入自己队列的底部。队列已满时返回错误 ErrQueueIsFull。
+func (w *Worker[E]) Push(value E) error {
+	return w.deque.push(value)
+}
+
+// Pop 取出任务。依次尝试自己队列的底部、注入队列，以及从其他工作者窃取。均无任务时返回错误 ErrQueueIsEmpty。
+func (w *Worker[E]) Pop() (E, error) {
+	if val, ok := w.deque.pop(); ok {
+		return val, nil
+	}
+	if val, _, err := w.ws.injector.Get(); err == nil {
+		return val, nil
+	}
+	workers := w.ws.workers
+	for i := 1; i < len(workers); i++ {
+		if val, ok := workers[(w.id+i)%len(workers)].deque.steal(); ok {
+			return val, nil
+		}
+	}
+	var empty E
+	return empty, ErrQueueIsEmpty
+}
+
+func (d *stealDeque[E]) push(value E) error {
+	b := atomic.LoadInt64(&d.bottom)
+	if b-atomic.LoadInt64(&d.top) > d.mask {
+		return ErrQueueIsFull
+	}
+	d.slots[b&d.mask].Store(&value)
+	atomic.StoreInt64(&d.bottom, b+1)
+	return nil
+}
+
+func (d *stealDeque[E]) pop() (E, bool) {
+	var empty E
+	b := atomic.LoadInt64(&d.bottom) - 1
+	atomic.StoreInt64(&d.bottom, b)
+	t := atomic.LoadInt64(&d.top)
+	if t > b {
+		atomic.StoreInt64(&d.bottom, b+1)
+		return empty, false
+	}
+	val := d.slots[b&d.mask].Load()
+	if t == b {
+		// 最后一个数据，与窃取者竞争。
+		won := atomic.CompareAndSwapInt64(&d.top, t, t+1)
+		atomic.StoreInt64(&d.bottom, b+1)
+		if !won {
+			return empty, false
+		}
+	}
+	d.release(b, val)
+	return *val, true
+}
+
+func (d *stealDeque[E]) steal() (E, bool) {
+	for attempt := 0; ; attempt++ {
+		t := atomic.LoadInt64(&d.top)
+		b := atomic.LoadInt64(&d.bottom)
+		if t >= b {
+			var empty E
+			return empty, false
+		}
+		val := d.slots[t&d.mask].Load()
+		if atomic.CompareAndSwapInt64(&d.top, t, t+1) {
+			d.release(t, val)
+			return *val, true
+		}
+		defaultBackoff.Wait(attempt)
+	}
+}
+
+// 清除已取出数据所在的槽位，使其可被回收。槽位已被重新填充时不清除。
+func (d *stealDeque[E]) release(index int64, val *E) {
+	if !d.keep {
+		d.slots[index&d.mask].CompareAndSwap(val, nil)
+	}
+}
+
+func (d *stealDeque[E]) len() int64 {
+	t := atomic.LoadInt64(&d.top)
+	if n := atomic.LoadInt64(&d.bottom) - t; n > 0 {
+		return n
+	}
+	return 0
+}
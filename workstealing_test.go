@@ -0,0 +1,127 @@
+/*
+ * Copyright (c) 2023 ivfzhou
+ * safe-queue is licensed under Mulan PSL v2.
+ * You can use this software according to the terms and conditions of the Mulan PSL v2.
+ * You may obtain a copy of Mulan PSL v2 at:
+ *          http://license.coscl.org.cn/MulanPSL2
+ * THIS SOFTWARE IS PROVIDED ON AN "AS IS" BASIS, WITHOUT WARRANTIES OF ANY KIND,
+ * EITHER EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO NON-INFRINGEMENT,
+ * MERCHANTABILITY OR FIT FOR A PARTICULAR PURPOSE.
+ * See the Mulan PSL v2 for more details.
+ */
+
+package safe_queue_test
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	queue "gitee.com/ivfzhou/safe-queue"
+)
+
+func TestWorkStealing(t *testing.T) {
+	ws := queue.NewWorkStealing[int](2, 4)
+	w0, w1 := ws.Worker(0), ws.Worker(1)
+	for i := 1; i <= 4; i++ {
+		if err := w0.Push(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w0.Push(5); err != queue.ErrQueueIsFull {
+		t.Fatal("err != ErrQueueIsFull")
+	}
+	if val, _ := w0.Pop(); val != 4 {
+		t.Fatal("owner pop not LIFO")
+	}
+	if val, _ := w1.Pop(); val != 1 {
+		t.Fatal("steal not FIFO")
+	}
+	ws.Submit(9)
+	if val, _ := w1.Pop(); val != 9 {
+		t.Fatal("injector not drained before stealing")
+	}
+	if ws.Len() != 2 {
+		t.Fatal("Len != 2")
+	}
+	w1.Pop()
+	w1.Pop()
+	if _, err := w1.Pop(); err != queue.ErrQueueIsEmpty {
+		t.Fatal("err != ErrQueueIsEmpty")
+	}
+}
+
+func TestWorkStealingConcurrent(t *testing.T) {
+	const (
+		workers = 4
+		count   = 20000
+	)
+	ws := queue.NewWorkStealing[int](workers, 64)
+	var (
+		sum  int64
+		done int64
+	)
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(w *queue.Worker[int]) {
+			defer wg.Done()
+			for atomic.LoadInt64(&done) < count {
+				val, err := w.Pop()
+				if err != nil {
+					runtime.Gosched()
+					continue
+				}
+				// 偶数任务派生一个子任务，由所属工作者放入自己的队列。
+				if val%2 == 0 && w.Push(val+1) != nil {
+					atomic.AddInt64(&sum, int64(val+1))
+					atomic.AddInt64(&done, 1)
+				}
+				atomic.AddInt64(&sum, int64(val))
+				atomic.AddInt64(&done, 1)
+			}
+		}(ws.Worker(i))
+	}
+	var want int64
+	for i := 0; i < count; i += 2 {
+		for {
+			if _, err := ws.Submit(i); err == nil {
+				break
+			}
+			runtime.Gosched()
+		}
+		want += int64(i + i + 1)
+	}
+	wg.Wait()
+	if sum != want {
+		t.Fatalf("sum %d != %d", sum, want)
+	}
+}
+
+func TestWorkStealingZeroing(t *testing.T) {
+	collected := func(opts ...queue.Option) bool {
+		ws := queue.NewWorkStealing[*[64]byte](2, 4, opts...)
+		var freed int32
+		for i := 0; i < 2; i++ {
+			v := new([64]byte)
+			runtime.SetFinalizer(v, func(*[64]byte) { atomic.AddInt32(&freed, 1) })
+			ws.Worker(0).Push(v)
+		}
+		ws.Worker(0).Pop()
+		ws.Worker(1).Pop()
+		for i := 0; i < 10 && atomic.LoadInt32(&freed) < 2; i++ {
+			runtime.GC()
+			time.Sleep(time.Millisecond)
+		}
+		runtime.KeepAlive(ws)
+		return atomic.LoadInt32(&freed) == 2
+	}
+	if !collected() {
+		t.Fatal("popped values not released")
+	}
+	if collected(queue.WithoutZeroing()) {
+		t.Fatal("slots zeroed")
+	}
+}